@@ -4,11 +4,13 @@ import (
 	"database/sql"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 	db "github.com/vladoohr/simple_bank/db/sqlc"
 	"github.com/vladoohr/simple_bank/token"
+	"github.com/vladoohr/simple_bank/util"
 )
 
 // createAccountRequest represents CreateAccount  user payload
@@ -75,7 +77,7 @@ func (server *Server) GetAccount(ctx *gin.Context) {
 	}
 
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if account.Owner != payload.Username {
+	if account.Owner != payload.Username && !util.CanViewAnyAccount(payload.Role) {
 		err := errors.New("accounts do not belong to the autheticated user")
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
@@ -84,10 +86,126 @@ func (server *Server) GetAccount(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, account)
 }
 
-// listAccountRequest holds the page number and page size
+// getAccountStatementRequest holds the time range of the statement
+type getAccountStatementRequest struct {
+	From time.Time `form:"from" binding:"required"`
+	To   time.Time `form:"to" binding:"required,gtfield=From"`
+}
+
+// accountStatementResponse holds the statement of an account
+type accountStatementResponse struct {
+	Account        db.Account         `json:"account"`
+	From           time.Time          `json:"from"`
+	To             time.Time          `json:"to"`
+	OpeningBalance int64              `json:"opening_balance"`
+	ClosingBalance int64              `json:"closing_balance"`
+	Lines          []db.StatementLine `json:"lines"`
+}
+
+// GetAccountStatement validates the request and returns the account entries within the time range
+// together with the opening, closing and running balances
+func (server *Server) GetAccountStatement(ctx *gin.Context) {
+	var uri getAccountRequest
+	var req getAccountStatementRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		} else {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != payload.Username && !util.CanViewAnyAccount(payload.Role) {
+		err := errors.New("accounts do not belong to the autheticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	statement, err := server.store.AccountStatementTx(ctx, db.AccountStatementTxParams{
+		AccountID: account.ID,
+		From:      req.From,
+		To:        req.To,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := accountStatementResponse{
+		Account:        account,
+		From:           req.From,
+		To:             req.To,
+		OpeningBalance: statement.OpeningBalance,
+		ClosingBalance: statement.ClosingBalance,
+		Lines:          statement.Lines,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// freezeAccountRequest holds the new frozen state of the account
+type freezeAccountRequest struct {
+	Frozen *bool `json:"frozen" binding:"required"`
+}
+
+// FreezeAccount freezes or unfreezes an account, so its balance can not be changed
+func (server *Server) FreezeAccount(ctx *gin.Context) {
+	var uri getAccountRequest
+	var req freezeAccountRequest
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.UpdateAccountFrozen(ctx, db.UpdateAccountFrozenParams{
+		ID:       uri.ID,
+		IsFrozen: *req.Frozen,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, account)
+}
+
+// listAccountRequest holds the page number or the page token and the page size
+// Without page_id the accounts are listed with cursor based pagination
 type listAccountRequest struct {
-	PageID   int32 `form:"page_id" binding:"min=1"`
-	PageSize int32 `form:"page_size" binding:"min=5,max=20"`
+	PageID    int32  `form:"page_id" binding:"omitempty,min=1"`
+	PageSize  int32  `form:"page_size" binding:"min=5,max=20"`
+	PageToken string `form:"page_token"`
+}
+
+// listAccountResponse holds a page of accounts and the token of the next page
+type listAccountResponse struct {
+	Accounts      []db.Account `json:"accounts"`
+	NextPageToken string       `json:"next_page_token,omitempty"`
 }
 
 // ListAccount validates the request and return a list of accounts
@@ -100,6 +218,18 @@ func (server *Server) ListAccount(ctx *gin.Context) {
 	}
 
 	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if req.PageID == 0 {
+		server.listAccountAfter(ctx, payload.Username, req)
+		return
+	}
+
+	if len(req.PageToken) > 0 {
+		err := errors.New("page_id and page_token cannot be used together")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
 	listAccountParams := db.ListAccountsParams{
 		Owner:  payload.Username,
 		Limit:  req.PageSize,
@@ -114,3 +244,36 @@ func (server *Server) ListAccount(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, accounts)
 }
+
+// listAccountAfter returns the page of accounts following the page token
+func (server *Server) listAccountAfter(ctx *gin.Context, owner string, req listAccountRequest) {
+	afterID, err := util.DecodePageToken(req.PageToken)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// fetch one more account to know if there is a next page
+	listAccountsAfterParams := db.ListAccountsAfterParams{
+		Owner:     owner,
+		AfterID:   afterID,
+		LimitSize: req.PageSize + 1,
+	}
+
+	accounts, err := server.store.ListAccountsAfter(ctx, listAccountsAfterParams)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := listAccountResponse{
+		Accounts: accounts,
+	}
+
+	if len(accounts) > int(req.PageSize) {
+		response.Accounts = accounts[:req.PageSize]
+		response.NextPageToken = util.EncodePageToken(response.Accounts[req.PageSize-1].ID)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 	mockdb "github.com/vladoohr/simple_bank/db/mock"
@@ -41,7 +42,7 @@ func TestGetAccount(t *testing.T) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, user.Username, time.Minute, authorizationTypeBearer, tokenMaker, request)
+				addAuthorization(t, user.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
 			},
 			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
 				require.Equal(t, recorder.Code, http.StatusOK)
@@ -56,13 +57,28 @@ func TestGetAccount(t *testing.T) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, "not_authorized", time.Minute, authorizationTypeBearer, tokenMaker, request)
+				addAuthorization(t, "not_authorized", util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
 			},
 			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 
 			},
 		},
+		{
+			name:      "BankerOK",
+			accountID: account.ID,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, "banker", util.BankerRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
 		{
 			name:      "NoAuthorization",
 			accountID: account.ID,
@@ -83,7 +99,7 @@ func TestGetAccount(t *testing.T) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(db.Account{}, sql.ErrNoRows).Times(1)
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, user.Username, time.Minute, authorizationTypeBearer, tokenMaker, request)
+				addAuthorization(t, user.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
 			},
 			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
 				require.Equal(t, recorder.Code, http.StatusNotFound)
@@ -96,7 +112,7 @@ func TestGetAccount(t *testing.T) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, user.Username, time.Minute, authorizationTypeBearer, tokenMaker, request)
+				addAuthorization(t, user.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
 			},
 			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
 				require.Equal(t, recorder.Code, http.StatusBadRequest)
@@ -109,7 +125,7 @@ func TestGetAccount(t *testing.T) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(db.Account{}, sql.ErrConnDone).Times(1)
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, user.Username, time.Minute, authorizationTypeBearer, tokenMaker, request)
+				addAuthorization(t, user.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
 			},
 			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
 				require.Equal(t, recorder.Code, http.StatusInternalServerError)
@@ -128,6 +144,7 @@ func TestGetAccount(t *testing.T) {
 
 		// build stubs
 		tc.buildStub(store)
+		expectAuthUser(store)
 
 		// start test server and send request
 		server := newTestServer(t, store)
@@ -145,6 +162,245 @@ func TestGetAccount(t *testing.T) {
 	}
 }
 
+func TestListAccount(t *testing.T) {
+	user, _ := randomUser(t)
+
+	n := 6
+	accounts := make([]db.Account, n)
+	for i := 0; i < n; i++ {
+		accounts[i] = randomAccount(user.Username)
+		accounts[i].ID = int64(i + 1)
+	}
+
+	pageSize := int32(5)
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OKPageID",
+			query: fmt.Sprintf("page_id=%d&page_size=%d", 1, pageSize),
+			buildStub: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsParams{
+					Owner:  user.Username,
+					Limit:  pageSize,
+					Offset: 0,
+				}
+
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).Return(accounts[:pageSize], nil).Times(1)
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotAccounts []db.Account
+				err := json.Unmarshal(recorder.Body.Bytes(), &gotAccounts)
+				require.NoError(t, err)
+				require.Equal(t, accounts[:pageSize], gotAccounts)
+			},
+		},
+		{
+			name:  "OKPageToken",
+			query: fmt.Sprintf("page_size=%d", pageSize),
+			buildStub: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsAfterParams{
+					Owner:     user.Username,
+					AfterID:   0,
+					LimitSize: pageSize + 1,
+				}
+
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Eq(arg)).Return(accounts, nil).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response listAccountResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Equal(t, accounts[:pageSize], response.Accounts)
+				require.Equal(t, util.EncodePageToken(accounts[pageSize-1].ID), response.NextPageToken)
+			},
+		},
+		{
+			name:  "OKLastPage",
+			query: fmt.Sprintf("page_size=%d&page_token=%s", pageSize, util.EncodePageToken(accounts[pageSize-1].ID)),
+			buildStub: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsAfterParams{
+					Owner:     user.Username,
+					AfterID:   accounts[pageSize-1].ID,
+					LimitSize: pageSize + 1,
+				}
+
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Eq(arg)).Return(accounts[pageSize:], nil).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response listAccountResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Equal(t, accounts[pageSize:], response.Accounts)
+				require.Empty(t, response.NextPageToken)
+			},
+		},
+		{
+			name:  "InvalidPageToken",
+			query: fmt.Sprintf("page_size=%d&page_token=%s", pageSize, "invalid!"),
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "PageIDWithPageToken",
+			query: fmt.Sprintf("page_id=%d&page_size=%d&page_token=%s", 1, pageSize, util.EncodePageToken(1)),
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts?%s", tc.query)
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, user.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, server.tokenMaker, req)
+			server.router.ServeHTTP(recorder, req)
+
+			tc.checkResponse(t, *recorder)
+		})
+	}
+}
+
+func TestGetAccountStatement(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	to := time.Now().UTC().Truncate(time.Second)
+	from := to.Add(-24 * time.Hour)
+
+	statement := db.AccountStatementTxResult{
+		OpeningBalance: 100,
+		ClosingBalance: 90,
+		Lines: []db.StatementLine{
+			{Entry: db.Entry{ID: 1, AccountID: account.ID, Amount: -10}, Balance: 90},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		from          time.Time
+		to            time.Time
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			from:     from,
+			to:       to,
+			buildStub: func(store *mockdb.MockStore) {
+				arg := db.AccountStatementTxParams{
+					AccountID: account.ID,
+					From:      from,
+					To:        to,
+				}
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().AccountStatementTx(gomock.Any(), gomock.Eq(arg)).Return(statement, nil).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response accountStatementResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.Equal(t, account, response.Account)
+				require.Equal(t, statement.OpeningBalance, response.OpeningBalance)
+				require.Equal(t, statement.ClosingBalance, response.ClosingBalance)
+				require.Equal(t, statement.Lines, response.Lines)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			username: "not_authorized",
+			from:     from,
+			to:       to,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().AccountStatementTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidRange",
+			username: user.Username,
+			from:     to,
+			to:       from,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().AccountStatementTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/statement", account.ID)
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			query := req.URL.Query()
+			query.Add("from", tc.from.Format(time.RFC3339))
+			query.Add("to", tc.to.Format(time.RFC3339))
+			req.URL.RawQuery = query.Encode()
+
+			addAuthorization(t, tc.username, util.DepositorRole, time.Minute, authorizationTypeBearer, server.tokenMaker, req)
+			server.router.ServeHTTP(recorder, req)
+
+			tc.checkResponse(t, *recorder)
+		})
+	}
+}
+
 func randomAccount(owner string) db.Account {
 	return db.Account{
 		ID:       util.RandomInt(1, 1000),
@@ -165,3 +421,102 @@ func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account db.Accoun
 	require.Equal(t, gotAccount, account)
 
 }
+
+func TestFreezeAccount(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	frozenAccount := account
+	frozenAccount.IsFrozen = true
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"frozen": true},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, "admin", util.AdminRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				arg := db.UpdateAccountFrozenParams{
+					ID:       account.ID,
+					IsFrozen: true,
+				}
+				store.EXPECT().UpdateAccountFrozen(gomock.Any(), gomock.Eq(arg)).Times(1).Return(frozenAccount, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, frozenAccount)
+			},
+		},
+		{
+			name: "NotAdmin",
+			body: gin.H{"frozen": true},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, user.Username, util.BankerRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountFrozen(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "MissingFrozen",
+			body: gin.H{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, "admin", util.AdminRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountFrozen(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			body: gin.H{"frozen": false},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, "admin", util.AdminRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountFrozen(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%d/freeze", account.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/token"
+)
+
+// balanceChangeRequest represents Deposit and Withdraw user payload
+type balanceChangeRequest struct {
+	Amount   int64  `json:"amount" binding:"required,gt=0"`
+	Currency string `json:"currency" binding:"required,currency"`
+}
+
+// Deposit adds money to any account, the route is restricted to the bank staff
+// The optional idempotency key makes the deposit safe to retry
+func (server *Server) Deposit(ctx *gin.Context) {
+	account, req, header, valid := server.validBalanceChange(ctx)
+	if !valid {
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	result, err := server.store.DepositTx(ctx, db.DepositTxParams{
+		AccountID:      account.ID,
+		Amount:         req.Amount,
+		Username:       payload.Username,
+		IdempotencyKey: header.IdempotencyKey,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrAccountFrozen) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, result)
+}
+
+// Withdraw takes money out of an account owned by the authenticated user
+// The optional idempotency key makes the withdrawal safe to retry
+func (server *Server) Withdraw(ctx *gin.Context) {
+	account, req, header, valid := server.validBalanceChange(ctx)
+	if !valid {
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if payload.Username != account.Owner {
+		err := errors.New("account does not belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.WithdrawTx(ctx, db.WithdrawTxParams{
+		AccountID:      account.ID,
+		Amount:         req.Amount,
+		Username:       payload.Username,
+		IdempotencyKey: header.IdempotencyKey,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+
+		if errors.Is(err, db.ErrAccountFrozen) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, result)
+}
+
+// validBalanceChange binds the request with its idempotency key and checks the account currency
+func (server *Server) validBalanceChange(ctx *gin.Context) (*db.Account, balanceChangeRequest, transferHeader, bool) {
+	var uri getAccountRequest
+	var req balanceChangeRequest
+	var header transferHeader
+
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, req, header, false
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, req, header, false
+	}
+
+	if err := ctx.ShouldBindHeader(&header); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return nil, req, header, false
+	}
+
+	account, valid := server.validAccount(ctx, uri.ID, req.Currency)
+	if !valid {
+		return nil, req, header, false
+	}
+
+	return account, req, header, true
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func TestDepositAndWithdraw(t *testing.T) {
+	amount := int64(10)
+
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	idempotencyKey := util.RandomString(16)
+
+	testCases := []struct {
+		name          string
+		path          string
+		username      string
+		role          string
+		header        map[string]string
+		body          gin.H
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "DepositOK",
+			path:     "deposit",
+			username: "banker",
+			role:     util.BankerRole,
+			body:     gin.H{"amount": amount, "currency": account.Currency},
+			buildStub: func(store *mockdb.MockStore) {
+				arg := db.DepositTxParams{
+					AccountID: account.ID,
+					Amount:    amount,
+					Username:  "banker",
+				}
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name:     "DepositIdempotencyKeyConflict",
+			path:     "deposit",
+			username: "banker",
+			role:     util.BankerRole,
+			header:   map[string]string{idempotencyKeyHeader: idempotencyKey},
+			body:     gin.H{"amount": amount, "currency": account.Currency},
+			buildStub: func(store *mockdb.MockStore) {
+				arg := db.DepositTxParams{
+					AccountID:      account.ID,
+					Amount:         amount,
+					Username:       "banker",
+					IdempotencyKey: idempotencyKey,
+				}
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Eq(arg)).Return(db.DepositTxResult{}, db.ErrIdempotencyKeyConflict).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:     "DepositForbiddenForDepositor",
+			path:     "deposit",
+			username: user.Username,
+			role:     util.DepositorRole,
+			body:     gin.H{"amount": amount, "currency": account.Currency},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "WithdrawOK",
+			path:     "withdraw",
+			username: user.Username,
+			role:     util.DepositorRole,
+			header:   map[string]string{idempotencyKeyHeader: idempotencyKey},
+			body:     gin.H{"amount": amount, "currency": account.Currency},
+			buildStub: func(store *mockdb.MockStore) {
+				arg := db.WithdrawTxParams{
+					AccountID:      account.ID,
+					Amount:         amount,
+					Username:       user.Username,
+					IdempotencyKey: idempotencyKey,
+				}
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name:     "WithdrawInsufficientFunds",
+			path:     "withdraw",
+			username: user.Username,
+			role:     util.DepositorRole,
+			body:     gin.H{"amount": amount, "currency": account.Currency},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).Return(db.WithdrawTxResult{}, db.ErrInsufficientFunds).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			path:     "withdraw",
+			username: "not_authorized",
+			role:     util.DepositorRole,
+			body:     gin.H{"amount": amount, "currency": account.Currency},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil).Times(1)
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidAmount",
+			path:     "deposit",
+			username: "banker",
+			role:     util.BankerRole,
+			body:     gin.H{"amount": -amount, "currency": account.Currency},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidCurrency",
+			path:     "withdraw",
+			username: user.Username,
+			role:     util.DepositorRole,
+			body:     gin.H{"amount": amount, "currency": "XYZ"},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().WithdrawTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/accounts/%d/%s", account.ID, tc.path)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			for key, value := range tc.header {
+				request.Header.Set(key, value)
+			}
+
+			addAuthorization(t, tc.username, tc.role, time.Minute, authorizationTypeBearer, server.tokenMaker, request)
+			server.router.ServeHTTP(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -8,13 +8,23 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
 	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/lockout"
+	"github.com/vladoohr/simple_bank/token"
 	"github.com/vladoohr/simple_bank/util"
 )
 
 func newTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute * 15,
+		TokenType:               token.PasetoTokenType,
+		TokenSymmetricKey:       util.RandomString(32),
+		TokenDenylist:           token.MemoryDenylistType,
+		LoginLimiter:            lockout.MemoryLimiterType,
+		LoginMaxAttempts:        3,
+		LoginMaxAttemptsPerIP:   10,
+		LoginLockoutDuration:    time.Minute,
+		LoginMaxLockoutDuration: time.Hour,
+		TOTPChallengeDuration:   time.Minute,
+		AccessTokenDuration:     time.Minute * 15,
 	}
 
 	server, err := NewServer(config, store)
@@ -1,12 +1,14 @@
 package api
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
 	"github.com/vladoohr/simple_bank/token"
 )
 
@@ -16,7 +18,11 @@ const (
 	authorizationPayloadKey = "authorization_payload"
 )
 
-func AuthMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+// AuthMiddleware authenticates the request with the bearer access token
+// Tokens issued before the last password change of the user and two-factor challenge tokens are rejected
+// If accessible roles are given, only users with one of these roles are allowed
+// Every authenticated request costs a denylist lookup and a GetUser query on top of the token verification
+func AuthMiddleware(tokenMaker token.Maker, denylist token.Denylist, store db.Querier, accessibleRoles ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
@@ -46,8 +52,59 @@ func AuthMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 			return
 		}
 
+		if payload.IsChallenge() {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(token.ErrChallengeToken))
+			return
+		}
+
+		revoked, err := denylist.Contains(ctx, payload.SessionID)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if revoked {
+			err := errors.New("session has been revoked")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		user, err := store.GetUser(ctx, payload.Username)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err := errors.New("user not found")
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if err := payload.CheckPasswordChange(user.PasswordChangeAt); err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		if len(accessibleRoles) > 0 && !hasRole(payload.Role, accessibleRoles) {
+			err := fmt.Errorf("role %s is not allowed to access this resource", payload.Role)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
 		ctx.Set(authorizationPayloadKey, payload)
 
 		ctx.Next()
 	}
 }
+
+// hasRole returns true if the role is one of the given roles
+func hasRole(role string, roles []string) bool {
+	for _, r := range roles {
+		if role == r {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,19 +10,25 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
 	"github.com/vladoohr/simple_bank/token"
+	"github.com/vladoohr/simple_bank/util"
 )
 
 func addAuthorization(
 	t *testing.T,
 	username string,
+	role string,
 	duration time.Duration,
 	authorizationType string,
 	tokenMaker token.Maker,
 	request *http.Request,
 ) {
-	tokenString, payload, err := tokenMaker.CreateToken(username, duration)
+	tokenString, payload, err := tokenMaker.CreateToken(username, role, uuid.New(), duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
@@ -28,17 +36,28 @@ func addAuthorization(
 	request.Header.Set(authorizationHeaderKey, authorizationHeader)
 }
 
+// expectAuthUser stubs the user lookup of the AuthMiddleware with a user that never changed the password
+func expectAuthUser(store *mockdb.MockStore) {
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, username string) (db.User, error) {
+			return db.User{Username: username, Role: util.DepositorRole}, nil
+		})
+}
+
 func TestAuthMiddleware(t *testing.T) {
 
 	testCases := []struct {
 		name          string
 		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStub     func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
 			name: "OK",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, "user", time.Minute, authorizationTypeBearer, tokenMaker, request)
+				addAuthorization(t, "user", util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -55,7 +74,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "UnsupportedAuthorization",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, "user", time.Minute, "unsupported", tokenMaker, request)
+				addAuthorization(t, "user", util.DepositorRole, time.Minute, "unsupported", tokenMaker, request)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -64,7 +83,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "InvalidAuthorizationFormat",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, "user", time.Minute, "", tokenMaker, request)
+				addAuthorization(t, "user", util.DepositorRole, time.Minute, "", tokenMaker, request)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -73,19 +92,128 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "ExpiredToken",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, "user", -time.Minute, authorizationTypeBearer, tokenMaker, request)
+				addAuthorization(t, "user", util.DepositorRole, -time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ChallengeToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, "user", token.ChallengeRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordChanged",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, "user", util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				user := db.User{Username: "user", PasswordChangeAt: time.Now().Add(time.Second)}
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq("user")).Times(1).Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotFound",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, "user", util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq("user")).Times(1).Return(db.User{}, sql.ErrNoRows)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, "user", util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq("user")).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
 	}
 
-	server := newTestServer(t, nil)
-	authPath := "/auth"
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			if tc.buildStub != nil {
+				tc.buildStub(store)
+			}
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				AuthMiddleware(server.tokenMaker, server.denylist, server.store),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, nil)
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authPath, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAuthMiddlewareRoles(t *testing.T) {
+
+	testCases := []struct {
+		name          string
+		role          string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			role: util.AdminRole,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Forbidden",
+			role: util.DepositorRole,
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	expectAuthUser(store)
+
+	server := newTestServer(t, store)
+	authPath := "/admin"
 	server.router.GET(
 		authPath,
-		AuthMiddleware(server.tokenMaker),
+		AuthMiddleware(server.tokenMaker, server.denylist, server.store, util.AdminRole),
 		func(ctx *gin.Context) {
 			ctx.JSON(http.StatusOK, nil)
 		},
@@ -97,7 +225,7 @@ func TestAuthMiddleware(t *testing.T) {
 			request, err := http.NewRequest(http.MethodGet, authPath, nil)
 			require.NoError(t, err)
 
-			tc.setupAuth(t, request, server.tokenMaker)
+			addAuthorization(t, "user", tc.role, time.Minute, authorizationTypeBearer, server.tokenMaker, request)
 
 			server.router.ServeHTTP(recorder, request)
 
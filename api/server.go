@@ -2,62 +2,117 @@ package api
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/lockout"
 	"github.com/vladoohr/simple_bank/token"
 	"github.com/vladoohr/simple_bank/util"
 )
 
 // Server server http requests
 type Server struct {
-	config     util.Config
-	store      db.Store
-	router     *gin.Engine
-	tokenMaker token.Maker
+	config               util.Config
+	store                db.Store
+	router               *gin.Engine
+	tokenMaker           token.Maker
+	denylist             token.Denylist
+	loginLimiter         lockout.Limiter
+	exchangeRateProvider util.ExchangeRateProvider
 }
 
 // NewServer creates new http sesrver and setup routing
 func NewServer(config util.Config, store db.Store) (*Server, error) {
 
-	tokenMaker, err := token.NewPasetoMaker([]byte(config.TokenSymmetricKey))
+	tokenMaker, err := token.NewMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create token maker: %w", err)
 	}
 
+	denylist, err := token.NewDenylist(config.TokenDenylist, store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token denylist: %w", err)
+	}
+
+	loginLimiter, err := lockout.NewLimiter(config.LoginLimiter, store, lockout.NewPolicy(config))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create login limiter: %w", err)
+	}
+
+	exchangeRateProvider, err := util.NewExchangeRateProvider(config.ExchangeRatesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exchange rate provider: %w", err)
+	}
+
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:               config,
+		store:                store,
+		tokenMaker:           tokenMaker,
+		denylist:             denylist,
+		loginLimiter:         loginLimiter,
+		exchangeRateProvider: exchangeRateProvider,
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", currencyValidator)
 	}
 
-	server.setUpRouter()
+	if err := server.setUpRouter(); err != nil {
+		return nil, err
+	}
 
 	return server, nil
 }
 
-func (server *Server) setUpRouter() {
+func (server *Server) setUpRouter() error {
 	router := gin.Default()
 
+	// the client IP of the login lockout and the sessions is taken from X-Forwarded-For only behind these proxies
+	if err := router.SetTrustedProxies(server.config.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
 	router.POST("/users", server.CreateUser)
 	router.POST("/users/login", server.LoginUser)
+	router.POST("/users/login/totp", server.LoginUserTOTP)
 	router.POST("/tokens/renew_access", server.RenewAccessToken)
 
-	authRoutes := router.Group("/").Use(AuthMiddleware(server.tokenMaker))
+	authRoutes := router.Group("/").Use(AuthMiddleware(server.tokenMaker, server.denylist, server.store))
 
 	authRoutes.POST("/accounts", server.CreateAccount)
 	authRoutes.GET("/accounts/:id", server.GetAccount)
 	authRoutes.GET("/accounts", server.ListAccount)
+	authRoutes.GET("/accounts/:id/statement", server.GetAccountStatement)
+	authRoutes.POST("/accounts/:id/withdraw", server.Withdraw)
 
 	authRoutes.POST("/transfers", server.CreateTransfer)
 
+	authRoutes.GET("/sessions", server.ListSessions)
+	authRoutes.DELETE("/sessions/:id", server.RevokeSession)
+	authRoutes.DELETE("/sessions", server.RevokeAllSessions)
+	authRoutes.POST("/users/logout", server.Logout)
+
+	// deposits are made by the bank staff, the account owners can only withdraw their money
+	bankerRoutes := router.Group("/").Use(AuthMiddleware(server.tokenMaker, server.denylist, server.store, util.BankerRole, util.AdminRole))
+
+	bankerRoutes.POST("/accounts/:id/deposit", server.Deposit)
+
+	adminRoutes := router.Group("/").Use(AuthMiddleware(server.tokenMaker, server.denylist, server.store, util.AdminRole))
+
+	adminRoutes.POST("/accounts/:id/freeze", server.FreezeAccount)
+	adminRoutes.POST("/sessions/:id/block", server.BlockSession)
+
 	server.router = router
+
+	return nil
+}
+
+// Handler returns the HTTP handler serving the API routes
+func (server *Server) Handler() http.Handler {
+	return server.router
 }
 
 // Start runs http server on  a specific address
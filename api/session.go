@@ -0,0 +1,193 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/token"
+)
+
+// sessionResponse does not return the refresh token of the session
+type sessionResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	UserAgent string    `json:"user_agent"`
+	ClientIp  string    `json:"client_ip"`
+	IsBlocked bool      `json:"is_blocked"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newSessionResponse(session db.Session) sessionResponse {
+	return sessionResponse{
+		ID:        session.ID,
+		Username:  session.Username,
+		UserAgent: session.UserAgent,
+		ClientIp:  session.ClientIp,
+		IsBlocked: session.IsBlocked,
+		ExpiresAt: session.ExpiresAt,
+		CreatedAt: session.CreatedAt,
+	}
+}
+
+// sessionRequest holds the ID of a session
+type sessionRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// BlockSession blocks a session together with the sessions rotated from the same login,
+// so none of their refresh tokens can renew access tokens anymore
+func (server *Server) BlockSession(ctx *gin.Context) {
+	var req sessionRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	session, err := server.store.BlockSession(ctx, uuid.MustParse(req.ID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := server.revokeSessionFamily(ctx, session); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newSessionResponse(session))
+}
+
+// ListSessions returns the active sessions of the authenticated user
+func (server *Server) ListSessions(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	sessions, err := server.store.ListActiveSessions(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, newSessionResponse(session))
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// RevokeSession blocks a session of the authenticated user
+func (server *Server) RevokeSession(ctx *gin.Context) {
+	var req sessionRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// sessions of other users are reported as not found
+	session, err := server.store.BlockUserSession(ctx, db.BlockUserSessionParams{
+		ID:       uuid.MustParse(req.ID),
+		Username: payload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := server.revokeSessionFamily(ctx, session); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newSessionResponse(session))
+}
+
+// revokeAllSessionsResponse holds the number of revoked sessions
+type revokeAllSessionsResponse struct {
+	RevokedCount int64 `json:"revoked_count"`
+}
+
+// RevokeAllSessions blocks all sessions of the authenticated user
+func (server *Server) RevokeAllSessions(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	sessions, err := server.store.BlockUserSessions(ctx, payload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := server.denySessions(ctx, sessions...); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, revokeAllSessionsResponse{RevokedCount: int64(len(sessions))})
+}
+
+// Logout blocks the session of the authenticated user, so its access and refresh tokens are rejected
+func (server *Server) Logout(ctx *gin.Context) {
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	session, err := server.store.BlockUserSession(ctx, db.BlockUserSessionParams{
+		ID:       payload.SessionID,
+		Username: payload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := server.revokeSessionFamily(ctx, session); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// revokeSessionFamily blocks the sessions rotated from the same login as the blocked session
+// and denies all of them, so older access tokens of the login are rejected as well
+func (server *Server) revokeSessionFamily(ctx *gin.Context, session db.Session) error {
+	family, err := server.store.BlockSessionFamily(ctx, session.FamilyID)
+	if err != nil {
+		return err
+	}
+
+	return server.denySessions(ctx, append(family, session)...)
+}
+
+// denySessions adds the blocked sessions to the denylist, so their access tokens are rejected right away
+func (server *Server) denySessions(ctx *gin.Context, sessions ...db.Session) error {
+	for _, session := range sessions {
+		if err := server.denylist.Add(ctx, session.ID, session.ExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
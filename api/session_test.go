@@ -0,0 +1,382 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/token"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func randomSession(username string) db.Session {
+	return db.Session{
+		ID:           uuid.New(),
+		Username:     username,
+		FamilyID:     uuid.New(),
+		RefreshToken: util.RandomString(32),
+		UserAgent:    util.RandomString(10),
+		ClientIp:     "127.0.0.1",
+		ExpiresAt:    time.Now().Add(time.Hour).Truncate(time.Second),
+		CreatedAt:    time.Now().Truncate(time.Second),
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	user, _ := randomUser(t)
+	sessions := []db.Session{randomSession(user.Username), randomSession(user.Username)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListActiveSessions(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(sessions, nil)
+
+	expectAuthUser(store)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/sessions", nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, user.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, server.tokenMaker, request)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	data, err := ioutil.ReadAll(recorder.Body)
+	require.NoError(t, err)
+
+	// the refresh tokens must not be returned
+	require.NotContains(t, string(data), sessions[0].RefreshToken)
+
+	var gotSessions []sessionResponse
+	err = json.Unmarshal(data, &gotSessions)
+	require.NoError(t, err)
+	require.Len(t, gotSessions, len(sessions))
+	for i, session := range sessions {
+		require.Equal(t, session.ID, gotSessions[i].ID)
+		require.Equal(t, session.UserAgent, gotSessions[i].UserAgent)
+		require.Equal(t, session.ClientIp, gotSessions[i].ClientIp)
+		require.WithinDuration(t, session.ExpiresAt, gotSessions[i].ExpiresAt, time.Second)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	user, _ := randomUser(t)
+	session := randomSession(user.Username)
+
+	blockedSession := session
+	blockedSession.IsBlocked = true
+
+	testCases := []struct {
+		name          string
+		sessionID     string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			sessionID: session.ID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, user.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				arg := db.BlockUserSessionParams{
+					ID:       session.ID,
+					Username: user.Username,
+				}
+				store.EXPECT().BlockUserSession(gomock.Any(), gomock.Eq(arg)).Times(1).Return(blockedSession, nil)
+				store.EXPECT().BlockSessionFamily(gomock.Any(), gomock.Eq(session.FamilyID)).Times(1).Return([]db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotSession sessionResponse
+				err := json.NewDecoder(recorder.Body).Decode(&gotSession)
+				require.NoError(t, err)
+				require.Equal(t, session.ID, gotSession.ID)
+				require.True(t, gotSession.IsBlocked)
+			},
+		},
+		{
+			name:      "NotFound",
+			sessionID: session.ID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, "other", util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockUserSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidID",
+			sessionID: "invalid",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, user.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockUserSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			sessionID: session.ID.String(),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockUserSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/sessions/%s", tc.sessionID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestBlockSessionFamily(t *testing.T) {
+	user, _ := randomUser(t)
+
+	// the refresh token of the old session was rotated into the blocked session
+	oldSession := randomSession(user.Username)
+	session := randomSession(user.Username)
+	session.FamilyID = oldSession.FamilyID
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	blockedSession := session
+	blockedSession.IsBlocked = true
+	blockedOldSession := oldSession
+	blockedOldSession.IsBlocked = true
+
+	store.EXPECT().
+		BlockSession(gomock.Any(), gomock.Eq(session.ID)).
+		Times(1).
+		Return(blockedSession, nil)
+	store.EXPECT().
+		BlockSessionFamily(gomock.Any(), gomock.Eq(session.FamilyID)).
+		Times(1).
+		Return([]db.Session{blockedOldSession}, nil)
+
+	expectAuthUser(store)
+
+	server := newTestServer(t, store)
+
+	oldAccessToken, _, err := server.tokenMaker.CreateToken(user.Username, util.DepositorRole, oldSession.ID, time.Minute)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	url := fmt.Sprintf("/sessions/%s/block", session.ID)
+	request, err := http.NewRequest(http.MethodPost, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, "admin", util.AdminRole, time.Minute, authorizationTypeBearer, server.tokenMaker, request)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var gotSession sessionResponse
+	err = json.NewDecoder(recorder.Body).Decode(&gotSession)
+	require.NoError(t, err)
+	require.Equal(t, session.ID, gotSession.ID)
+	require.True(t, gotSession.IsBlocked)
+
+	// the access token issued before the rotation is rejected as well
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/sessions", nil)
+	require.NoError(t, err)
+
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, oldAccessToken))
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	user, _ := randomUser(t)
+
+	sessions := []db.Session{randomSession(user.Username), randomSession(user.Username), randomSession(user.Username)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		BlockUserSessions(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(sessions, nil)
+
+	expectAuthUser(store)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodDelete, "/sessions", nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, user.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, server.tokenMaker, request)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response revokeAllSessionsResponse
+	err = json.NewDecoder(recorder.Body).Decode(&response)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(sessions)), response.RevokedCount)
+
+	for _, session := range sessions {
+		revoked, err := server.denylist.Contains(context.Background(), session.ID)
+		require.NoError(t, err)
+		require.True(t, revoked)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	user, _ := randomUser(t)
+	session := randomSession(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	blockedSession := session
+	blockedSession.IsBlocked = true
+
+	arg := db.BlockUserSessionParams{
+		ID:       session.ID,
+		Username: user.Username,
+	}
+	store.EXPECT().
+		BlockUserSession(gomock.Any(), gomock.Eq(arg)).
+		Times(1).
+		Return(blockedSession, nil)
+	store.EXPECT().
+		BlockSessionFamily(gomock.Any(), gomock.Eq(session.FamilyID)).
+		Times(1).
+		Return([]db.Session{}, nil)
+
+	expectAuthUser(store)
+
+	server := newTestServer(t, store)
+
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, util.DepositorRole, session.ID, time.Minute)
+	require.NoError(t, err)
+
+	authorizationHeader := fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/users/logout", nil)
+	require.NoError(t, err)
+
+	request.Header.Set(authorizationHeaderKey, authorizationHeader)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+
+	// the access token of the blocked session is rejected
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/sessions", nil)
+	require.NoError(t, err)
+
+	request.Header.Set(authorizationHeaderKey, authorizationHeader)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestLogoutRotatedSession(t *testing.T) {
+	user, _ := randomUser(t)
+
+	// the refresh token of the old session was rotated into the current session
+	oldSession := randomSession(user.Username)
+	session := randomSession(user.Username)
+	session.FamilyID = oldSession.FamilyID
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	blockedSession := session
+	blockedSession.IsBlocked = true
+	blockedOldSession := oldSession
+	blockedOldSession.IsBlocked = true
+
+	store.EXPECT().
+		BlockUserSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(blockedSession, nil)
+	store.EXPECT().
+		BlockSessionFamily(gomock.Any(), gomock.Eq(oldSession.FamilyID)).
+		Times(1).
+		Return([]db.Session{blockedOldSession}, nil)
+
+	expectAuthUser(store)
+
+	server := newTestServer(t, store)
+
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, util.DepositorRole, session.ID, time.Minute)
+	require.NoError(t, err)
+
+	oldAccessToken, _, err := server.tokenMaker.CreateToken(user.Username, util.DepositorRole, oldSession.ID, time.Minute)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/users/logout", nil)
+	require.NoError(t, err)
+
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+
+	// the access token issued before the rotation is rejected as well
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/sessions", nil)
+	require.NoError(t, err)
+
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, oldAccessToken))
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
 )
 
 // renewAccessTokenRequest holds the refresh token
@@ -14,13 +16,20 @@ type renewAccessTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
-// renewAccessTokenResponse holds access token information
+// renewAccessTokenResponse holds the new access and refresh tokens information
 type renewAccessTokenResponse struct {
-	AccessToken          string    `json:"access_token"`
-	AccessTokenExpiredAt time.Time `json:"access_token_expired_at"`
+	SessionID             uuid.UUID `json:"session_id"`
+	AccessToken           string    `json:"access_token"`
+	AccessTokenExpiredAt  time.Time `json:"access_token_expired_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiredAt time.Time `json:"refresh_token_expired_at"`
 }
 
 // Renew the access token based on the given refresh token
+// The refresh token is rotated, so each one can be used only once
+// Reusing a rotated refresh token blocks all sessions of its family
+// Refresh tokens issued before the last password change are rejected
+// The new tokens carry the current role of the user, so role changes apply on the next renewal
 func (server *Server) RenewAccessToken(ctx *gin.Context) {
 	var req renewAccessTokenRequest
 
@@ -35,7 +44,7 @@ func (server *Server) RenewAccessToken(ctx *gin.Context) {
 		return
 	}
 
-	session, err := server.store.GetSession(ctx, tokenPayload.ID)
+	session, err := server.store.GetSession(ctx, tokenPayload.SessionID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
@@ -69,15 +78,64 @@ func (server *Server) RenewAccessToken(ctx *gin.Context) {
 		return
 	}
 
-	accessToken, accessTokenPayload, err := server.tokenMaker.CreateToken(tokenPayload.Username, server.config.AccessTokenDuration)
+	user, err := server.store.GetUser(ctx, tokenPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := tokenPayload.CheckPasswordChange(user.PasswordChangeAt); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	newSessionID := uuid.New()
+
+	refreshToken, refreshTokenPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, newSessionID, server.config.RefreshTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.RotateSessionTx(ctx, db.RotateSessionTxParams{
+		SessionID: session.ID,
+		NewSession: db.CreateSessionParams{
+			ID:           newSessionID,
+			Username:     tokenPayload.Username,
+			RefreshToken: refreshToken,
+			UserAgent:    ctx.Request.UserAgent(),
+			ClientIp:     ctx.ClientIP(),
+			IsBlocked:    false,
+			ExpiresAt:    refreshTokenPayload.ExpireAt,
+		},
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRefreshTokenReused) {
+			if err := server.denySessions(ctx, result.BlockedSessions...); err != nil {
+				ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	accessToken, accessTokenPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, result.Session.ID, server.config.AccessTokenDuration)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
 	response := renewAccessTokenResponse{
-		AccessToken:          accessToken,
-		AccessTokenExpiredAt: accessTokenPayload.ExpireAt,
+		SessionID:             result.Session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiredAt:  accessTokenPayload.ExpireAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiredAt: refreshTokenPayload.ExpireAt,
 	}
 
 	ctx.JSON(http.StatusOK, response)
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func TestRenewAccessToken(t *testing.T) {
+	user, _ := randomUser(t)
+	user.Role = util.DepositorRole
+	session := randomSession(user.Username)
+	session.FamilyID = session.ID
+
+	testCases := []struct {
+		name          string
+		setupSession  func(session *db.Session)
+		buildStub     func(store *mockdb.MockStore, session db.Session)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:         "OK",
+			setupSession: func(session *db.Session) {},
+			buildStub: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RotateSessionTxParams) (db.RotateSessionTxResult, error) {
+						require.Equal(t, session.ID, arg.SessionID)
+						require.Equal(t, user.Username, arg.NewSession.Username)
+						require.NotEqual(t, session.RefreshToken, arg.NewSession.RefreshToken)
+
+						newSession := db.Session{
+							ID:           arg.NewSession.ID,
+							Username:     arg.NewSession.Username,
+							RefreshToken: arg.NewSession.RefreshToken,
+							ExpiresAt:    arg.NewSession.ExpiresAt,
+							FamilyID:     session.FamilyID,
+						}
+						return db.RotateSessionTxResult{Session: newSession}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response renewAccessTokenResponse
+				err := json.NewDecoder(recorder.Body).Decode(&response)
+				require.NoError(t, err)
+				require.NotEqual(t, session.ID, response.SessionID)
+				require.NotEmpty(t, response.RefreshToken)
+
+				payload, err := server.tokenMaker.VerifyToken(response.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, response.SessionID, payload.SessionID)
+			},
+		},
+		{
+			// the refresh token was issued when the user was an admin
+			name:         "RoleChanged",
+			setupSession: func(session *db.Session) {},
+			buildStub: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.RotateSessionTxParams) (db.RotateSessionTxResult, error) {
+						return db.RotateSessionTxResult{Session: db.Session{ID: arg.NewSession.ID}}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response renewAccessTokenResponse
+				err := json.NewDecoder(recorder.Body).Decode(&response)
+				require.NoError(t, err)
+
+				accessPayload, err := server.tokenMaker.VerifyToken(response.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, util.DepositorRole, accessPayload.Role)
+			},
+		},
+		{
+			name:         "RefreshTokenReused",
+			setupSession: func(session *db.Session) {},
+			buildStub: func(store *mockdb.MockStore, session db.Session) {
+				blockedSession := session
+				blockedSession.IsBlocked = true
+
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RotateSessionTxResult{BlockedSessions: []db.Session{blockedSession}}, db.ErrRefreshTokenReused)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+				revoked, err := server.denylist.Contains(context.Background(), session.ID)
+				require.NoError(t, err)
+				require.True(t, revoked)
+			},
+		},
+		{
+			name: "BlockedSession",
+			setupSession: func(session *db.Session) {
+				session.IsBlocked = true
+			},
+			buildStub: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().RotateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:         "PasswordChanged",
+			setupSession: func(session *db.Session) {},
+			buildStub: func(store *mockdb.MockStore, session db.Session) {
+				changedUser := user
+				changedUser.PasswordChangeAt = time.Now().Add(time.Second)
+
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(changedUser, nil)
+				store.EXPECT().RotateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredSession",
+			setupSession: func(session *db.Session) {
+				session.ExpiresAt = time.Now().Add(-time.Minute)
+			},
+			buildStub: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().RotateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			refreshToken, _, err := server.tokenMaker.CreateToken(user.Username, util.AdminRole, session.ID, time.Minute)
+			require.NoError(t, err)
+
+			tokenSession := session
+			tokenSession.RefreshToken = refreshToken
+			tc.setupSession(&tokenSession)
+			tc.buildStub(store, tokenSession)
+
+			data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, server, recorder)
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	db "github.com/vladoohr/simple_bank/db/sqlc"
 	"github.com/vladoohr/simple_bank/token"
+	"github.com/vladoohr/simple_bank/util"
 )
 
 // transferRequest represents TransferAccount user payload
@@ -19,15 +20,28 @@ type transferRequest struct {
 	Currency      string `json:"currency" binding:"required,currency"`
 }
 
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// transferHeader holds the optional idempotency key of the transfer, deposit or withdrawal
+type transferHeader struct {
+	IdempotencyKey string `header:"Idempotency-Key" binding:"max=255"`
+}
+
 // createTransfer transfers amount of money from one account to another
 func (server *Server) CreateTransfer(ctx *gin.Context) {
 	var req transferRequest
+	var header transferHeader
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
+	if err := ctx.ShouldBindHeader(&header); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
 	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
 	if !valid {
 		return
@@ -40,19 +54,51 @@ func (server *Server) CreateTransfer(ctx *gin.Context) {
 		return
 	}
 
-	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	toAccount, valid := server.getAccount(ctx, req.ToAccountID)
 	if !valid {
 		return
 	}
 
 	transferTxParams := db.TransferTxParams{
-		FromAccountId: req.FromAccountID,
-		ToAccountId:   req.ToAccountID,
-		Amount:        req.Amount,
+		FromAccountId:  req.FromAccountID,
+		ToAccountId:    req.ToAccountID,
+		Amount:         req.Amount,
+		Username:       payload.Username,
+		IdempotencyKey: header.IdempotencyKey,
 	}
 
-	result, err := server.store.TransferTx(ctx, transferTxParams)
+	var result db.TransferTxResult
+	var err error
+
+	if toAccount.Currency == fromAccount.Currency {
+		result, err = server.store.TransferTx(ctx, transferTxParams)
+	} else {
+		rate, valid := server.exchangeRate(ctx, req.Amount, fromAccount.Currency, toAccount.Currency)
+		if !valid {
+			return
+		}
+
+		result, err = server.store.ExchangeTransferTx(ctx, db.ExchangeTransferTxParams{
+			TransferTxParams: transferTxParams,
+			ExchangeRate:     rate,
+		})
+	}
 	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+
+		if errors.Is(err, db.ErrAccountFrozen) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -61,6 +107,22 @@ func (server *Server) CreateTransfer(ctx *gin.Context) {
 }
 
 func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (*db.Account, bool) {
+	account, valid := server.getAccount(ctx, accountID)
+	if !valid {
+		return nil, false
+	}
+
+	if account.Currency != currency {
+		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", accountID, account.Currency, currency)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+
+		return nil, false
+	}
+
+	return account, true
+}
+
+func (server *Server) getAccount(ctx *gin.Context, accountID int64) (*db.Account, bool) {
 	account, err := server.store.GetAccount(ctx, accountID)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -72,12 +134,23 @@ func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency s
 		return nil, false
 	}
 
-	if account.Currency != currency {
-		err = fmt.Errorf("account [%d] currency mismatch: %s vs %s", accountID, account.Currency, currency)
+	return &account, true
+}
+
+// exchangeRate returns the rate to convert the amount between the currencies
+// and checks that the converted amount is not rounded down to zero
+func (server *Server) exchangeRate(ctx *gin.Context, amount int64, from string, to string) (float64, bool) {
+	rate, err := server.exchangeRateProvider.GetExchangeRate(from, to)
+	if err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return 0, false
+	}
 
-		return nil, false
+	if util.ConvertAmount(amount, rate) <= 0 {
+		err := fmt.Errorf("amount %d %s is too small to be converted to %s", amount, from, to)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return 0, false
 	}
 
-	return &account, true
+	return rate, true
 }
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/token"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func TestCreateTransfer(t *testing.T) {
+	amount := int64(10)
+
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account2.ID = account1.ID + 1
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+
+	account3 := randomAccount(user2.Username)
+	account3.ID = account1.ID + 2
+	account3.Currency = util.EUR
+
+	idempotencyKey := util.RandomString(16)
+
+	testCases := []struct {
+		name           string
+		body           gin.H
+		idempotencyKey string
+		setupAuth      func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStub      func(store *mockdb.MockStore)
+		checkResponse  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, user1.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				arg := db.TransferTxParams{
+					FromAccountId: account1.ID,
+					ToAccountId:   account2.ID,
+					Amount:        amount,
+					Username:      user1.Username,
+				}
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Return(account2, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "ExchangeTransfer",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account3.ID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, user1.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account3.ID)).Return(account3, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ExchangeTransferTx(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ interface{}, arg db.ExchangeTransferTxParams) (db.TransferTxResult, error) {
+						require.Equal(t, account1.ID, arg.FromAccountId)
+						require.Equal(t, account3.ID, arg.ToAccountId)
+						require.Equal(t, amount, arg.Amount)
+						require.Positive(t, arg.ExchangeRate)
+						return db.TransferTxResult{}, nil
+					}).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "IdempotencyKey",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			idempotencyKey: idempotencyKey,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, user1.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				arg := db.TransferTxParams{
+					FromAccountId:  account1.ID,
+					ToAccountId:    account2.ID,
+					Amount:         amount,
+					Username:       user1.Username,
+					IdempotencyKey: idempotencyKey,
+				}
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Return(account2, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "IdempotencyKeyConflict",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			idempotencyKey: idempotencyKey,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, user1.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Return(account2, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{}, db.ErrIdempotencyKeyConflict).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "IdempotencyKeyTooLong",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			idempotencyKey: util.RandomString(256),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, user1.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, user1.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Return(account2, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Return(db.TransferTxResult{}, db.ErrInsufficientFunds).Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, user2.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "FromAccountNotFound",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, user1.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(db.Account{}, sql.ErrNoRows).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        util.EUR,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, user1.Username, util.DepositorRole, time.Minute, authorizationTypeBearer, tokenMaker, request)
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Return(account1, nil).Times(1)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          amount,
+				"currency":        util.USD,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+			expectAuthUser(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/transfers"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			if len(tc.idempotencyKey) > 0 {
+				request.Header.Set(idempotencyKeyHeader, tc.idempotencyKey)
+			}
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -3,14 +3,25 @@ package api
 import (
 	"database/sql"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/token"
 	"github.com/vladoohr/simple_bank/util"
+	"github.com/vladoohr/simple_bank/worker"
+)
+
+var (
+	// errInvalidCredentials is returned for both unknown usernames and wrong passwords
+	errInvalidCredentials = errors.New("invalid credentials")
+	// errLoginLocked is returned while the username or the client IP is locked out
+	errLoginLocked = errors.New("too many failed login attempts, try again later")
 )
 
 // createUserRequest represents the User payload
@@ -26,6 +37,7 @@ type userResponse struct {
 	Username         string    `json:"username"`
 	Email            string    `json:"email"`
 	FullName         string    `json:"full_name"`
+	Role             string    `json:"role"`
 	PasswordChangeAt time.Time `json:"password_change_at"`
 	CreatedAt        time.Time `json:"created_at"`
 }
@@ -36,6 +48,20 @@ type loginUserRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 }
 
+// loginChallengeResponse is returned instead of the tokens when the user has two-factor authentication enabled
+type loginChallengeResponse struct {
+	TOTPRequired            bool      `json:"totp_required"`
+	ChallengeToken          string    `json:"challenge_token"`
+	ChallengeTokenExpiredAt time.Time `json:"challenge_token_expired_at"`
+}
+
+// loginUserTOTPRequest represents the second step of the two-factor login
+// Code is either the current TOTP code or one of the recovery codes
+type loginUserTOTPRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required,max=32"`
+}
+
 // loginUserResponse holds access token and user information
 type loginUserResponse struct {
 	SessionID             uuid.UUID    `json:"session_id"`
@@ -51,6 +77,7 @@ func newUserResponse(user db.User) userResponse {
 		Username:         user.Username,
 		FullName:         user.FullName,
 		Email:            user.Email,
+		Role:             user.Role,
 		PasswordChangeAt: user.PasswordChangeAt,
 		CreatedAt:        user.CreatedAt,
 	}
@@ -71,14 +98,23 @@ func (server *Server) CreateUser(ctx *gin.Context) {
 		return
 	}
 
-	createUserParams := db.CreateUserParams{
-		Username:       req.Username,
-		HashedPassword: hashedPassword,
-		FullName:       req.Fullname,
-		Email:          req.Email,
+	verifyEmailTask, err := worker.NewSendVerifyEmailTask(worker.PayloadSendVerifyEmail{Username: req.Username})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	createUserTxParams := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			HashedPassword: hashedPassword,
+			FullName:       req.Fullname,
+			Email:          req.Email,
+		},
+		Tasks: []db.CreateTaskParams{verifyEmailTask},
 	}
 
-	user, err := server.store.CreateUser(ctx, createUserParams)
+	result, err := server.store.CreateUserTx(ctx, createUserTxParams)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			switch pqErr.Code.Name() {
@@ -92,7 +128,7 @@ func (server *Server) CreateUser(ctx *gin.Context) {
 		return
 	}
 
-	response := newUserResponse(user)
+	response := newUserResponse(result.User)
 
 	ctx.JSON(http.StatusCreated, response)
 }
@@ -100,6 +136,8 @@ func (server *Server) CreateUser(ctx *gin.Context) {
 // LoginUser validates the request, checks if user exists,
 // checks the password and generates the JWT or PASETO access token
 // Returns the generated access token and user information
+// Failed attempts are counted per username and client IP, which are locked after too many of them
+// Users with two-factor authentication get a challenge token instead, see LoginUserTOTP
 func (server *Server) LoginUser(ctx *gin.Context) {
 	var req loginUserRequest
 
@@ -108,42 +146,96 @@ func (server *Server) LoginUser(ctx *gin.Context) {
 		return
 	}
 
+	clientIP := ctx.ClientIP()
+
+	lockedUntil, err := server.loginLimiter.LockedUntil(ctx, req.Username, clientIP)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !lockedUntil.IsZero() {
+		respondLoginLocked(ctx, lockedUntil)
+		return
+	}
+
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		if !errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+
+		util.SimulatePasswordCheck(req.Password)
+		server.rejectLogin(ctx, req.Username, clientIP)
 		return
 	}
 
 	err = util.CheckPassword(req.Password, user.HashedPassword)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		server.rejectLogin(ctx, req.Username, clientIP)
+		return
+	}
+
+	if user.IsTotpEnabled {
+		server.challengeLogin(ctx, user)
+		return
+	}
+
+	server.startLoginSession(ctx, user)
+}
+
+// challengeLogin responds with a short-lived challenge token, which is exchanged
+// for the session tokens together with a TOTP or recovery code in LoginUserTOTP
+// The failed attempts are not reset until the second factor is verified
+func (server *Server) challengeLogin(ctx *gin.Context, user db.User) {
+	challengeToken, challengePayload, err := server.tokenMaker.CreateToken(user.Username, token.ChallengeRole, uuid.Nil, server.config.TOTPChallengeDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	response := loginChallengeResponse{
+		TOTPRequired:            true,
+		ChallengeToken:          challengeToken,
+		ChallengeTokenExpiredAt: challengePayload.ExpireAt,
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// startLoginSession resets the failed login attempts, creates new session
+// and responds with its access and refresh tokens
+func (server *Server) startLoginSession(ctx *gin.Context, user db.User) {
+	if err := server.loginLimiter.RecordSuccess(ctx, user.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	accessToken, accessTokenPayload, err := server.tokenMaker.CreateToken(req.Username, server.config.AccessTokenDuration)
+	// both tokens are tied to the new session
+	sessionID := uuid.New()
+
+	accessToken, accessTokenPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, sessionID, server.config.AccessTokenDuration)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	refreshToken, refreshTokenPayload, err := server.tokenMaker.CreateToken(req.Username, server.config.RefreshTokenDuration)
+	refreshToken, refreshTokenPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, sessionID, server.config.RefreshTokenDuration)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
 	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
-		ID:           refreshTokenPayload.ID,
+		ID:           sessionID,
 		Username:     refreshTokenPayload.Username,
 		RefreshToken: refreshToken,
 		UserAgent:    ctx.Request.UserAgent(),
 		ClientIp:     ctx.ClientIP(),
 		IsBlocked:    false,
 		ExpiresAt:    refreshTokenPayload.ExpireAt,
+		FamilyID:     sessionID,
 	})
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -161,3 +253,112 @@ func (server *Server) LoginUser(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, response)
 }
+
+// rejectLogin records the failed login attempt and responds with the same error
+// whether the username does not exist or the password is wrong
+func (server *Server) rejectLogin(ctx *gin.Context, username string, clientIP string) {
+	if _, err := server.loginLimiter.RecordFailure(ctx, username, clientIP); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+}
+
+// respondLoginLocked tells the client when the login can be retried
+func respondLoginLocked(ctx *gin.Context, lockedUntil time.Time) {
+	retryAfter := int(math.Ceil(time.Until(lockedUntil).Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+	ctx.JSON(http.StatusTooManyRequests, errorResponse(errLoginLocked))
+}
+
+// LoginUserTOTP verifies the challenge token from LoginUser and the TOTP or recovery code of the user
+// and responds with the session tokens like LoginUser, each challenge token can be exchanged for a session only once
+// Wrong codes count as failed login attempts, so the codes cannot be guessed while the challenge is valid
+func (server *Server) LoginUserTOTP(ctx *gin.Context) {
+	var req loginUserTOTPRequest
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := server.tokenMaker.VerifyToken(req.ChallengeToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !payload.IsChallenge() {
+		err := errors.New("token is not a challenge token")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	clientIP := ctx.ClientIP()
+
+	lockedUntil, err := server.loginLimiter.LockedUntil(ctx, payload.Username, clientIP)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !lockedUntil.IsZero() {
+		respondLoginLocked(ctx, lockedUntil)
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := payload.CheckPasswordChange(user.PasswordChangeAt); err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !user.IsTotpEnabled {
+		err := errors.New("two-factor authentication is not enabled")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.UseLoginCodeTx(ctx, db.UseLoginCodeTxParams{
+		User: user,
+		Code: req.Code,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.rejectLogin(ctx, user.Username, clientIP)
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.UseChallengeTx(ctx, db.UseChallengeTxParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiredAt: payload.ExpireAt,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err := errors.New("challenge token has already been used")
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.startLoginSession(ctx, user)
+}
@@ -2,19 +2,27 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	mockdb "github.com/vladoohr/simple_bank/db/mock"
 	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/lockout"
+	"github.com/vladoohr/simple_bank/token"
 	"github.com/vladoohr/simple_bank/util"
+	"github.com/vladoohr/simple_bank/worker"
 )
 
 // eqUserParams holds user params need to compate the passwords and the CreateUserParams objects
@@ -23,14 +31,15 @@ type eqUserParams struct {
 	password string
 }
 
-// EqUser returns a matcher that matches on equality for db.User object.
+// EqUser returns a matcher that matches on equality for db.User object
+// and expects a verification email to be enqueued for the user
 func eqUser(arg db.CreateUserParams, password string) gomock.Matcher {
 	return eqUserParams{arg, password}
 }
 
 // Matches checks that the hashed password and raw pass
 func (e eqUserParams) Matches(x interface{}) bool {
-	arg, ok := x.(db.CreateUserParams)
+	arg, ok := x.(db.CreateUserTxParams)
 	if !ok {
 		return false
 	}
@@ -40,8 +49,12 @@ func (e eqUserParams) Matches(x interface{}) bool {
 		return false
 	}
 
+	if len(arg.Tasks) != 1 || arg.Tasks[0].Type != worker.TaskSendVerifyEmail {
+		return false
+	}
+
 	e.arg.HashedPassword = arg.HashedPassword
-	return reflect.DeepEqual(e.arg, arg)
+	return reflect.DeepEqual(e.arg, arg.CreateUserParams)
 }
 
 // String describes what the matcher matches.
@@ -66,7 +79,7 @@ func TestCreateUser(t *testing.T) {
 		FullName: user.FullName,
 		Email:    user.Email,
 	}
-	store.EXPECT().CreateUser(gomock.Any(), eqUser(createUserParams, password)).Return(user, nil).Times(1)
+	store.EXPECT().CreateUserTx(gomock.Any(), eqUser(createUserParams, password)).Return(db.CreateUserTxResult{User: user}, nil).Times(1)
 
 	// start test server and send request
 	server := newTestServer(t, store)
@@ -106,3 +119,345 @@ func randomUser(t *testing.T) (user db.User, password string) {
 
 	return
 }
+
+func TestLoginUser(t *testing.T) {
+	user, password := randomUser(t)
+
+	totpUser := user
+	totpUser.IsTotpEnabled = true
+
+	testCases := []struct {
+		name          string
+		username      string
+		password      string
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			password: password,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "TOTPRequired",
+			username: user.Username,
+			password: password,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(totpUser, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var response loginChallengeResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &response)
+				require.NoError(t, err)
+				require.True(t, response.TOTPRequired)
+				require.NotEmpty(t, response.ChallengeToken)
+			},
+		},
+		{
+			name:     "UserNotFound",
+			username: "unknown",
+			password: password,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq("unknown")).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name:     "WrongPassword",
+			username: user.Username,
+			password: "wrong_password",
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request := newLoginRequest(t, tc.username, tc.password)
+			server.router.ServeHTTP(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginUserLockout(t *testing.T) {
+	user, password := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	maxAttempts := int(server.config.LoginMaxAttempts)
+
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(maxAttempts).Return(user, nil)
+	store.EXPECT().
+		CreateAuditEvent(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateAuditEventParams) (db.AuditEvent, error) {
+			require.Equal(t, lockout.LockoutEventType, arg.EventType)
+			require.Equal(t, user.Username, arg.Username)
+
+			return db.AuditEvent{ID: 1}, nil
+		})
+
+	for i := 0; i < maxAttempts; i++ {
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, newLoginRequest(t, user.Username, "wrong_password"))
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	}
+
+	// the right password is rejected as well until the lockout ends
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, newLoginRequest(t, user.Username, password))
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	retryAfter, err := strconv.Atoi(recorder.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	require.InDelta(t, server.config.LoginLockoutDuration.Seconds(), retryAfter, 1)
+}
+
+func TestLoginUserLockoutSpoofedClientIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	maxAttempts := int(server.config.LoginMaxAttemptsPerIP)
+	remoteIP := "192.0.2.1"
+
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(maxAttempts).Return(db.User{}, sql.ErrNoRows)
+	store.EXPECT().
+		CreateAuditEvent(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateAuditEventParams) (db.AuditEvent, error) {
+			require.Equal(t, remoteIP, arg.ClientIp)
+
+			return db.AuditEvent{ID: 1}, nil
+		})
+
+	// every attempt claims another client IP, but the request does not come from a trusted proxy
+	for i := 0; i <= maxAttempts; i++ {
+		request := newLoginRequest(t, util.RandomOwner(), "wrong_password")
+		request.RemoteAddr = remoteIP + ":1234"
+		request.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+
+		if i < maxAttempts {
+			require.Equal(t, http.StatusUnauthorized, recorder.Code)
+		} else {
+			require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		}
+	}
+}
+
+func TestLoginUserTOTP(t *testing.T) {
+	user, _ := randomUser(t)
+	user.IsTotpEnabled = true
+
+	secret, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+	user.TotpSecret = secret
+
+	validCode := func(t *testing.T) string {
+		code, err := util.GenerateTOTPCode(secret, time.Now())
+		require.NoError(t, err)
+		return code
+	}
+
+	challengeToken := func(t *testing.T, tokenMaker token.Maker) string {
+		challenge, _, err := tokenMaker.CreateToken(user.Username, token.ChallengeRole, uuid.Nil, time.Minute)
+		require.NoError(t, err)
+		return challenge
+	}
+
+	testCases := []struct {
+		name          string
+		token         func(t *testing.T, tokenMaker token.Maker) string
+		code          func(t *testing.T) string
+		buildStub     func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			token: challengeToken,
+			code:  validCode,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					UseLoginCodeTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseLoginCodeTxParams) (db.UseLoginCodeTxResult, error) {
+						require.Equal(t, user, arg.User)
+						require.NotEmpty(t, arg.Code)
+
+						return db.UseLoginCodeTxResult{User: user}, nil
+					})
+				store.EXPECT().
+					UseChallengeTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseChallengeTxParams) (db.UsedChallenge, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NotEqual(t, uuid.Nil, arg.ID)
+
+						return db.UsedChallenge{ID: arg.ID}, nil
+					})
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "access_token")
+			},
+		},
+		{
+			name:  "WrongCode",
+			token: challengeToken,
+			code:  validCode,
+			buildStub: func(store *mockdb.MockStore) {
+				// the code is wrong or has already been used
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseLoginCodeTx(gomock.Any(), gomock.Any()).Times(1).Return(db.UseLoginCodeTxResult{}, sql.ErrNoRows)
+				store.EXPECT().UseChallengeTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name:  "UsedChallenge",
+			token: challengeToken,
+			code:  validCode,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseLoginCodeTx(gomock.Any(), gomock.Any()).Times(1).Return(db.UseLoginCodeTxResult{User: user}, nil)
+				store.EXPECT().UseChallengeTx(gomock.Any(), gomock.Any()).Times(1).Return(db.UsedChallenge{}, sql.ErrNoRows)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "challenge token has already been used")
+			},
+		},
+		{
+			name:  "InternalError",
+			token: challengeToken,
+			code:  validCode,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseLoginCodeTx(gomock.Any(), gomock.Any()).Times(1).Return(db.UseLoginCodeTxResult{}, sql.ErrConnDone)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "AccessToken",
+			token: func(t *testing.T, tokenMaker token.Maker) string {
+				accessToken, _, err := tokenMaker.CreateToken(user.Username, util.DepositorRole, uuid.New(), time.Minute)
+				require.NoError(t, err)
+				return accessToken
+			},
+			code: validCode,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:  "PasswordChanged",
+			token: challengeToken,
+			code:  validCode,
+			buildStub: func(store *mockdb.MockStore) {
+				changedUser := user
+				changedUser.PasswordChangeAt = time.Now().Add(time.Second)
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(changedUser, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStub(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"challenge_token": tc.token(t, server.tokenMaker),
+				"code":            tc.code(t),
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/totp", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func newLoginRequest(t *testing.T, username string, password string) *http.Request {
+	data, err := json.Marshal(gin.H{
+		"username": username,
+		"password": password,
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	return request
+}
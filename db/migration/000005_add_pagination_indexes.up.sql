@@ -0,0 +1,3 @@
+CREATE INDEX "accounts_owner_id_idx" ON "accounts" ("owner", "id");
+
+CREATE INDEX "entries_account_id_id_idx" ON "entries" ("account_id", "id");
@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "exchange_rate";
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" double precision NOT NULL DEFAULT 1;
+
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'rate applied to credit the amount in the destination account currency';
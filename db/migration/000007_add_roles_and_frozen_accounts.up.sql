@@ -0,0 +1,7 @@
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'depositor';
+
+ALTER TABLE "accounts" ADD COLUMN "is_frozen" boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN "users"."role" IS 'depositor, banker or admin';
+
+COMMENT ON COLUMN "accounts"."is_frozen" IS 'frozen accounts can not be debited or credited';
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "tasks";
+
+DROP TABLE IF EXISTS "verify_emails" CASCADE;
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "is_email_verified";
\ No newline at end of file
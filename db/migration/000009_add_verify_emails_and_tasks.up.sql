@@ -0,0 +1,32 @@
+ALTER TABLE "users" ADD COLUMN "is_email_verified" boolean NOT NULL DEFAULT false;
+
+CREATE TABLE "verify_emails" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "email" varchar NOT NULL,
+  "secret_code" varchar NOT NULL,
+  "is_used" boolean NOT NULL DEFAULT false,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "expired_at" timestamptz NOT NULL DEFAULT (now() + interval '15 minutes')
+);
+
+ALTER TABLE "verify_emails" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "tasks" (
+  "id" bigserial PRIMARY KEY,
+  "type" varchar NOT NULL,
+  "payload" jsonb NOT NULL DEFAULT '{}',
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "attempts" integer NOT NULL DEFAULT 0,
+  "max_attempts" integer NOT NULL DEFAULT 5,
+  "last_error" varchar NOT NULL DEFAULT '',
+  "run_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "tasks" ("status", "run_at");
+
+COMMENT ON COLUMN "tasks"."status" IS 'pending, running, done or failed';
+
+COMMENT ON COLUMN "tasks"."run_at" IS 'the task is not picked up by the worker before this time';
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE "password_resets" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "token_hash" varchar UNIQUE NOT NULL,
+  "is_used" boolean NOT NULL DEFAULT false,
+  "created_at" timestamp NOT NULL DEFAULT (now()),
+  "expired_at" timestamptz NOT NULL
+);
+
+ALTER TABLE "password_resets" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+COMMENT ON COLUMN "password_resets"."token_hash" IS 'sha256 of the reset token, the token itself is only sent to the user';
\ No newline at end of file
@@ -0,0 +1,23 @@
+CREATE TABLE "login_attempts" (
+  "kind" varchar NOT NULL,
+  "key" varchar NOT NULL,
+  "failed_count" integer NOT NULL DEFAULT 0,
+  "last_failed_at" timestamptz NOT NULL DEFAULT (now()),
+  "locked_until" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z',
+  PRIMARY KEY ("kind", "key")
+);
+
+CREATE TABLE "audit_events" (
+  "id" bigserial PRIMARY KEY,
+  "event_type" varchar NOT NULL,
+  "username" varchar NOT NULL DEFAULT '',
+  "client_ip" varchar NOT NULL DEFAULT '',
+  "details" jsonb NOT NULL DEFAULT '{}',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "audit_events" ("event_type", "created_at");
+
+COMMENT ON COLUMN "login_attempts"."kind" IS 'username or client_ip';
+
+COMMENT ON COLUMN "audit_events"."username" IS 'not a foreign key, failed logins are audited for unknown usernames too';
\ No newline at end of file
@@ -0,0 +1,19 @@
+ALTER TABLE "users" ADD COLUMN "totp_secret" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "users" ADD COLUMN "is_totp_enabled" boolean NOT NULL DEFAULT false;
+
+CREATE TABLE "recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "hashed_code" varchar NOT NULL,
+  "is_used" boolean NOT NULL DEFAULT false,
+  "created_at" timestamp NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "recovery_codes" ("username");
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+COMMENT ON COLUMN "users"."totp_secret" IS 'base32 TOTP secret, set on enrollment and only used once is_totp_enabled is true';
+
+COMMENT ON COLUMN "recovery_codes"."hashed_code" IS 'bcrypt hash of the recovery code, the code itself is only shown to the user once';
\ No newline at end of file
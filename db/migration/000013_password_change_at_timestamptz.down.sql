@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS "users" ALTER COLUMN "password_change_at" TYPE timestamp USING "password_change_at" AT TIME ZONE 'UTC';
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "users" ALTER COLUMN "password_change_at" TYPE timestamptz USING "password_change_at" AT TIME ZONE 'UTC';
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "used_challenges";
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "totp_last_step";
\ No newline at end of file
@@ -0,0 +1,16 @@
+ALTER TABLE "users" ADD COLUMN "totp_last_step" bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE "used_challenges" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "expired_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "used_challenges" ("expired_at");
+
+ALTER TABLE "used_challenges" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+COMMENT ON COLUMN "users"."totp_last_step" IS 'TOTP time step of the last accepted code, codes of this or an earlier step are rejected';
+
+COMMENT ON COLUMN "used_challenges"."id" IS 'payload ID of a two-factor challenge token, which can be exchanged for a session only once';
\ No newline at end of file
@@ -25,6 +25,12 @@ ORDER BY id
 LIMIT $2
 OFFSET $3;
 
+-- name: ListAccountsAfter :many
+SELECT * FROM accounts
+WHERE owner = sqlc.arg(owner) AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(limit_size);
+
 -- name: UpdateAccount :one
 UPDATE accounts 
 SET balance = $2
@@ -37,5 +43,20 @@ SET balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
+-- name: UpdateAccountFrozen :one
+UPDATE accounts
+SET is_frozen = sqlc.arg(is_frozen)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE FROM accounts WHERE id = $1;
+
+-- name: ListAccountBalancesAfter :many
+SELECT a.id, a.owner, a.currency, a.balance, COALESCE(SUM(e.amount), 0)::bigint AS entries_balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id > sqlc.arg(after_id)
+GROUP BY a.id
+ORDER BY a.id
+LIMIT sqlc.arg(limit_size);
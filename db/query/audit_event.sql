@@ -0,0 +1,9 @@
+-- name: CreateAuditEvent :one
+INSERT INTO audit_events (
+    event_type,
+    username,
+    client_ip,
+    details
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
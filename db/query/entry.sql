@@ -16,4 +16,22 @@ SELECT * FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
-OFFSET $3;
\ No newline at end of file
+OFFSET $3;
+
+-- name: ListEntriesAfter :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id) AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(limit_size);
+
+-- name: GetAccountBalanceAt :one
+SELECT (a.balance - COALESCE(SUM(e.amount), 0))::bigint AS balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id AND e.created_at >= sqlc.arg(at_time)
+WHERE a.id = sqlc.arg(account_id)
+GROUP BY a.id;
+
+-- name: ListEntriesBetween :many
+SELECT * FROM entries
+WHERE account_id = sqlc.arg(account_id) AND created_at >= sqlc.arg(from_time) AND created_at < sqlc.arg(to_time)
+ORDER BY id;
@@ -0,0 +1,33 @@
+-- name: RecordLoginFailure :one
+-- the count starts over when the last failure is older than reset_before
+INSERT INTO login_attempts (
+    kind,
+    key,
+    failed_count,
+    last_failed_at
+) VALUES (
+    sqlc.arg(kind), sqlc.arg(key), 1, now()
+)
+ON CONFLICT (kind, key) DO UPDATE
+SET
+    failed_count = CASE
+        WHEN login_attempts.last_failed_at < sqlc.arg(reset_before) THEN 1
+        ELSE login_attempts.failed_count + 1
+    END,
+    last_failed_at = now()
+RETURNING *;
+
+-- name: UpdateLoginLockout :exec
+UPDATE login_attempts
+SET locked_until = $3
+WHERE kind = $1 AND key = $2;
+
+-- name: ListLoginAttempts :many
+SELECT * FROM login_attempts
+WHERE
+    (kind = 'username' AND key = sqlc.arg(username))
+    OR (kind = 'client_ip' AND key = sqlc.arg(client_ip));
+
+-- name: DeleteLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE kind = $1 AND key = $2;
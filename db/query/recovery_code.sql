@@ -0,0 +1,27 @@
+-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+    username,
+    hashed_code
+) VALUES (
+    $1, $2
+) RETURNING *;
+
+-- name: ListUnusedRecoveryCodes :many
+SELECT * FROM recovery_codes
+WHERE
+    username = $1
+    AND is_used = FALSE
+ORDER BY id;
+
+-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+SET
+    is_used = TRUE
+WHERE
+    id = $1
+    AND is_used = FALSE
+RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
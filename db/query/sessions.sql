@@ -6,9 +6,10 @@ INSERT INTO sessions (
     user_agent,
     client_ip,
     is_blocked,
-    expires_at
+    expires_at,
+    family_id
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6, $7, $8
 ) RETURNING *;
 
 
@@ -16,3 +17,38 @@ INSERT INTO sessions (
 SELECT * FROM sessions
 WHERE id = $1
 LIMIT 1;
+
+-- name: BlockSession :one
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1
+RETURNING *;
+
+-- name: ListActiveSessions :many
+SELECT * FROM sessions
+WHERE username = $1 AND is_blocked = false AND rotated_at IS NULL AND expires_at > now()
+ORDER BY created_at DESC;
+
+-- name: BlockUserSession :one
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1 AND username = $2
+RETURNING *;
+
+-- name: BlockUserSessions :many
+UPDATE sessions
+SET is_blocked = true
+WHERE username = $1 AND is_blocked = false
+RETURNING *;
+
+-- name: RotateSession :one
+UPDATE sessions
+SET rotated_at = now()
+WHERE id = $1 AND rotated_at IS NULL
+RETURNING *;
+
+-- name: BlockSessionFamily :many
+UPDATE sessions
+SET is_blocked = true
+WHERE family_id = $1 AND is_blocked = false
+RETURNING *;
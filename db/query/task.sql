@@ -0,0 +1,51 @@
+-- name: CreateTask :one
+INSERT INTO tasks (
+    type,
+    payload,
+    max_attempts,
+    run_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ClaimTasks :many
+-- running tasks not updated since stale_before are picked up again, so a crashed worker does not lose them
+UPDATE tasks
+SET
+    status = 'running',
+    attempts = attempts + 1,
+    updated_at = now()
+WHERE id IN (
+    SELECT t.id FROM tasks t
+    WHERE
+        (t.status = 'pending' AND t.run_at <= now())
+        OR (t.status = 'running' AND t.updated_at < sqlc.arg(stale_before))
+    ORDER BY t.run_at
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteTask :exec
+UPDATE tasks
+SET
+    status = 'done',
+    updated_at = now()
+WHERE id = $1;
+
+-- name: RetryTask :exec
+UPDATE tasks
+SET
+    status = 'pending',
+    last_error = $2,
+    run_at = $3,
+    updated_at = now()
+WHERE id = $1;
+
+-- name: FailTask :exec
+UPDATE tasks
+SET
+    status = 'failed',
+    last_error = $2,
+    updated_at = now()
+WHERE id = $1;
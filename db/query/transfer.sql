@@ -3,9 +3,10 @@
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  exchange_rate
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: GetTransfer :one
@@ -19,4 +20,13 @@ WHERE
     to_account_id = $2
 ORDER BY id
 LIMIT $3
-OFFSET $4;
\ No newline at end of file
+OFFSET $4;
+
+-- name: ListTransfersAfter :many
+SELECT * FROM transfers
+WHERE 
+    (from_account_id = sqlc.arg(from_account_id) OR
+    to_account_id = sqlc.arg(to_account_id)) AND
+    id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(limit_size);
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- name: CreateUsedChallenge :one
+INSERT INTO used_challenges (
+    id,
+    username,
+    expired_at
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (id) DO NOTHING
+RETURNING *;
+
+-- name: DeleteExpiredUsedChallenges :exec
+DELETE FROM used_challenges
+WHERE expired_at < now();
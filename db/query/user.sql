@@ -13,13 +13,44 @@ SELECT * FROM users
 WHERE username = $1
 LIMIT 1;
 
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1
+LIMIT 1;
+
 -- name: UpdateUser :one
 UPDATE  users
 SET
     hashed_password = COALESCE(sqlc.narg(hashed_password), hashed_password),
     password_change_at = COALESCE(sqlc.narg(password_change_at), password_change_at),
     email = COALESCE(sqlc.narg(email), email),
-    full_name = COALESCE(sqlc.narg(full_name), full_name)
+    full_name = COALESCE(sqlc.narg(full_name), full_name),
+    is_email_verified = CASE
+        WHEN sqlc.narg(email) IS NOT NULL AND sqlc.narg(email) <> email THEN FALSE
+        ELSE COALESCE(sqlc.narg(is_email_verified), is_email_verified)
+    END,
+    totp_secret = COALESCE(sqlc.narg(totp_secret), totp_secret),
+    is_totp_enabled = COALESCE(sqlc.narg(is_totp_enabled), is_totp_enabled),
+    totp_last_step = COALESCE(sqlc.narg(totp_last_step), totp_last_step)
 WHERE 
     username = sqlc.arg(username)
 RETURNING *;
+
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET
+    is_email_verified = TRUE
+WHERE
+    username = sqlc.arg(username)
+    AND email = sqlc.arg(email)
+RETURNING *;
+
+-- name: UseTOTPStep :one
+UPDATE users
+SET
+    totp_last_step = sqlc.arg(step)
+WHERE
+    username = sqlc.arg(username)
+    AND totp_last_step < sqlc.arg(step)
+RETURNING *;
@@ -13,7 +13,7 @@ const addAccountBalance = `-- name: AddAccountBalance :one
 UPDATE accounts 
 SET balance = balance + $1
 WHERE id = $2
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, is_frozen
 `
 
 type AddAccountBalanceParams struct {
@@ -30,6 +30,7 @@ func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalancePa
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.IsFrozen,
 	)
 	return i, err
 }
@@ -41,7 +42,7 @@ INSERT INTO accounts (
     currency
 ) VALUES (
     $1, $2, $3
-) RETURNING id, owner, balance, currency, created_at
+) RETURNING id, owner, balance, currency, created_at, is_frozen
 `
 
 type CreateAccountParams struct {
@@ -59,6 +60,7 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.IsFrozen,
 	)
 	return i, err
 }
@@ -73,7 +75,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, is_frozen FROM accounts
 WHERE id = $1
 LIMIT 1
 `
@@ -87,12 +89,13 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.IsFrozen,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, is_frozen FROM accounts
 WHERE id = $1
 LIMIT 1
 FOR NO KEY UPDATE
@@ -107,12 +110,65 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.IsFrozen,
 	)
 	return i, err
 }
 
+const listAccountBalancesAfter = `-- name: ListAccountBalancesAfter :many
+SELECT a.id, a.owner, a.currency, a.balance, COALESCE(SUM(e.amount), 0)::bigint AS entries_balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id > $1
+GROUP BY a.id
+ORDER BY a.id
+LIMIT $2
+`
+
+type ListAccountBalancesAfterParams struct {
+	AfterID   int64 `json:"after_id"`
+	LimitSize int32 `json:"limit_size"`
+}
+
+type ListAccountBalancesAfterRow struct {
+	ID             int64  `json:"id"`
+	Owner          string `json:"owner"`
+	Currency       string `json:"currency"`
+	Balance        int64  `json:"balance"`
+	EntriesBalance int64  `json:"entries_balance"`
+}
+
+func (q *Queries) ListAccountBalancesAfter(ctx context.Context, arg ListAccountBalancesAfterParams) ([]ListAccountBalancesAfterRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountBalancesAfter, arg.AfterID, arg.LimitSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountBalancesAfterRow{}
+	for rows.Next() {
+		var i ListAccountBalancesAfterRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Currency,
+			&i.Balance,
+			&i.EntriesBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, is_frozen FROM accounts
 WHERE owner = $1
 ORDER BY id
 LIMIT $2
@@ -140,6 +196,50 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.IsFrozen,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountsAfter = `-- name: ListAccountsAfter :many
+SELECT id, owner, balance, currency, created_at, is_frozen FROM accounts
+WHERE owner = $1 AND id > $2
+ORDER BY id
+LIMIT $3
+`
+
+type ListAccountsAfterParams struct {
+	Owner     string `json:"owner"`
+	AfterID   int64  `json:"after_id"`
+	LimitSize int32  `json:"limit_size"`
+}
+
+func (q *Queries) ListAccountsAfter(ctx context.Context, arg ListAccountsAfterParams) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountsAfter, arg.Owner, arg.AfterID, arg.LimitSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.IsFrozen,
 		); err != nil {
 			return nil, err
 		}
@@ -158,7 +258,7 @@ const updateAccount = `-- name: UpdateAccount :one
 UPDATE accounts 
 SET balance = $2
 WHERE id = $1
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, is_frozen
 `
 
 type UpdateAccountParams struct {
@@ -175,6 +275,33 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.IsFrozen,
+	)
+	return i, err
+}
+
+const updateAccountFrozen = `-- name: UpdateAccountFrozen :one
+UPDATE accounts
+SET is_frozen = $1
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, is_frozen
+`
+
+type UpdateAccountFrozenParams struct {
+	IsFrozen bool  `json:"is_frozen"`
+	ID       int64 `json:"id"`
+}
+
+func (q *Queries) UpdateAccountFrozen(ctx context.Context, arg UpdateAccountFrozenParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, updateAccountFrozen, arg.IsFrozen, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.IsFrozen,
 	)
 	return i, err
 }
@@ -11,10 +11,14 @@ import (
 )
 
 func createRandomAccount(t *testing.T) Account {
+	return createRandomAccountWithBalance(t, util.RandomBalance())
+}
+
+func createRandomAccountWithBalance(t *testing.T, balance int64) Account {
 	user := createRandomUser(t)
 	createAccountParams := CreateAccountParams{
 		Owner:    user.Username,
-		Balance:  util.RandomBalance(),
+		Balance:  balance,
 		Currency: util.RandomCurrency(),
 	}
 
@@ -104,3 +108,34 @@ func TestListAccounts(t *testing.T) {
 		require.Equal(t, lastAccount.Owner, account.Owner)
 	}
 }
+
+func TestListAccountsAfter(t *testing.T) {
+	user := createRandomUser(t)
+
+	var accounts []Account
+	for _, currency := range []string{util.USD, util.EUR, util.CAN} {
+		account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  util.RandomBalance(),
+			Currency: currency,
+		})
+		require.NoError(t, err)
+
+		accounts = append(accounts, account)
+	}
+
+	listAccountsAfterParams := ListAccountsAfterParams{
+		Owner:     user.Username,
+		AfterID:   accounts[0].ID,
+		LimitSize: 5,
+	}
+
+	gotAccounts, err := testQueries.ListAccountsAfter(context.Background(), listAccountsAfterParams)
+	require.NoError(t, err)
+	require.Len(t, gotAccounts, 2)
+
+	for i, account := range gotAccounts {
+		require.Equal(t, accounts[i+1].ID, account.ID)
+		require.Equal(t, user.Username, account.Owner)
+	}
+}
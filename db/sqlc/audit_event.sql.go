@@ -0,0 +1,48 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: audit_event.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+)
+
+const createAuditEvent = `-- name: CreateAuditEvent :one
+INSERT INTO audit_events (
+    event_type,
+    username,
+    client_ip,
+    details
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, event_type, username, client_ip, details, created_at
+`
+
+type CreateAuditEventParams struct {
+	EventType string          `json:"event_type"`
+	Username  string          `json:"username"`
+	ClientIp  string          `json:"client_ip"`
+	Details   json.RawMessage `json:"details"`
+}
+
+func (q *Queries) CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error) {
+	row := q.db.QueryRowContext(ctx, createAuditEvent,
+		arg.EventType,
+		arg.Username,
+		arg.ClientIp,
+		arg.Details,
+	)
+	var i AuditEvent
+	err := row.Scan(
+		&i.ID,
+		&i.EventType,
+		&i.Username,
+		&i.ClientIp,
+		&i.Details,
+		&i.CreatedAt,
+	)
+	return i, err
+}
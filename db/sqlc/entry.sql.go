@@ -7,6 +7,7 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 const createEntry = `-- name: CreateEntry :one
@@ -35,6 +36,26 @@ func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry
 	return i, err
 }
 
+const getAccountBalanceAt = `-- name: GetAccountBalanceAt :one
+SELECT (a.balance - COALESCE(SUM(e.amount), 0))::bigint AS balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id AND e.created_at >= $1
+WHERE a.id = $2
+GROUP BY a.id
+`
+
+type GetAccountBalanceAtParams struct {
+	AtTime    time.Time `json:"at_time"`
+	AccountID int64     `json:"account_id"`
+}
+
+func (q *Queries) GetAccountBalanceAt(ctx context.Context, arg GetAccountBalanceAtParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getAccountBalanceAt, arg.AtTime, arg.AccountID)
+	var balance int64
+	err := row.Scan(&balance)
+	return balance, err
+}
+
 const getEntry = `-- name: GetEntry :one
 SELECT id, account_id, amount, created_at FROM entries
 WHERE id = $1
@@ -94,3 +115,84 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 	}
 	return items, nil
 }
+
+const listEntriesAfter = `-- name: ListEntriesAfter :many
+SELECT id, account_id, amount, created_at FROM entries
+WHERE account_id = $1 AND id > $2
+ORDER BY id
+LIMIT $3
+`
+
+type ListEntriesAfterParams struct {
+	AccountID int64 `json:"account_id"`
+	AfterID   int64 `json:"after_id"`
+	LimitSize int32 `json:"limit_size"`
+}
+
+func (q *Queries) ListEntriesAfter(ctx context.Context, arg ListEntriesAfterParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listEntriesAfter, arg.AccountID, arg.AfterID, arg.LimitSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEntriesBetween = `-- name: ListEntriesBetween :many
+SELECT id, account_id, amount, created_at FROM entries
+WHERE account_id = $1 AND created_at >= $2 AND created_at < $3
+ORDER BY id
+`
+
+type ListEntriesBetweenParams struct {
+	AccountID int64     `json:"account_id"`
+	FromTime  time.Time `json:"from_time"`
+	ToTime    time.Time `json:"to_time"`
+}
+
+func (q *Queries) ListEntriesBetween(ctx context.Context, arg ListEntriesBetweenParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listEntriesBetween, arg.AccountID, arg.FromTime, arg.ToTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
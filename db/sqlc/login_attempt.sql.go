@@ -0,0 +1,123 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: login_attempt.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const deleteLoginAttempts = `-- name: DeleteLoginAttempts :exec
+DELETE FROM login_attempts
+WHERE kind = $1 AND key = $2
+`
+
+type DeleteLoginAttemptsParams struct {
+	Kind string `json:"kind"`
+	Key  string `json:"key"`
+}
+
+func (q *Queries) DeleteLoginAttempts(ctx context.Context, arg DeleteLoginAttemptsParams) error {
+	_, err := q.db.ExecContext(ctx, deleteLoginAttempts, arg.Kind, arg.Key)
+	return err
+}
+
+const listLoginAttempts = `-- name: ListLoginAttempts :many
+SELECT kind, key, failed_count, last_failed_at, locked_until FROM login_attempts
+WHERE
+    (kind = 'username' AND key = $1)
+    OR (kind = 'client_ip' AND key = $2)
+`
+
+type ListLoginAttemptsParams struct {
+	Username string `json:"username"`
+	ClientIp string `json:"client_ip"`
+}
+
+func (q *Queries) ListLoginAttempts(ctx context.Context, arg ListLoginAttemptsParams) ([]LoginAttempt, error) {
+	rows, err := q.db.QueryContext(ctx, listLoginAttempts, arg.Username, arg.ClientIp)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LoginAttempt{}
+	for rows.Next() {
+		var i LoginAttempt
+		if err := rows.Scan(
+			&i.Kind,
+			&i.Key,
+			&i.FailedCount,
+			&i.LastFailedAt,
+			&i.LockedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :one
+INSERT INTO login_attempts (
+    kind,
+    key,
+    failed_count,
+    last_failed_at
+) VALUES (
+    $1, $2, 1, now()
+)
+ON CONFLICT (kind, key) DO UPDATE
+SET
+    failed_count = CASE
+        WHEN login_attempts.last_failed_at < $3 THEN 1
+        ELSE login_attempts.failed_count + 1
+    END,
+    last_failed_at = now()
+RETURNING kind, key, failed_count, last_failed_at, locked_until
+`
+
+type RecordLoginFailureParams struct {
+	Kind        string    `json:"kind"`
+	Key         string    `json:"key"`
+	ResetBefore time.Time `json:"reset_before"`
+}
+
+// the count starts over when the last failure is older than reset_before
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginAttempt, error) {
+	row := q.db.QueryRowContext(ctx, recordLoginFailure, arg.Kind, arg.Key, arg.ResetBefore)
+	var i LoginAttempt
+	err := row.Scan(
+		&i.Kind,
+		&i.Key,
+		&i.FailedCount,
+		&i.LastFailedAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const updateLoginLockout = `-- name: UpdateLoginLockout :exec
+UPDATE login_attempts
+SET locked_until = $3
+WHERE kind = $1 AND key = $2
+`
+
+type UpdateLoginLockoutParams struct {
+	Kind        string    `json:"kind"`
+	Key         string    `json:"key"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+func (q *Queries) UpdateLoginLockout(ctx context.Context, arg UpdateLoginLockoutParams) error {
+	_, err := q.db.ExecContext(ctx, updateLoginLockout, arg.Kind, arg.Key, arg.LockedUntil)
+	return err
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func TestRecordLoginFailure(t *testing.T) {
+	username := util.RandomOwner()
+
+	arg := RecordLoginFailureParams{
+		Kind:        "username",
+		Key:         username,
+		ResetBefore: time.Now().Add(-time.Hour),
+	}
+
+	loginAttempt, err := testQueries.RecordLoginFailure(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), loginAttempt.FailedCount)
+
+	loginAttempt, err = testQueries.RecordLoginFailure(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), loginAttempt.FailedCount)
+
+	lockedUntil := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	err = testQueries.UpdateLoginLockout(context.Background(), UpdateLoginLockoutParams{
+		Kind:        "username",
+		Key:         username,
+		LockedUntil: lockedUntil,
+	})
+	require.NoError(t, err)
+
+	loginAttempts, err := testQueries.ListLoginAttempts(context.Background(), ListLoginAttemptsParams{
+		Username: username,
+		ClientIp: util.RandomString(8),
+	})
+	require.NoError(t, err)
+	require.Len(t, loginAttempts, 1)
+	require.WithinDuration(t, lockedUntil, loginAttempts[0].LockedUntil, time.Second)
+
+	// failures older than reset_before are forgotten
+	arg.ResetBefore = time.Now().Add(time.Hour)
+	loginAttempt, err = testQueries.RecordLoginFailure(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), loginAttempt.FailedCount)
+
+	err = testQueries.DeleteLoginAttempts(context.Background(), DeleteLoginAttemptsParams{Kind: "username", Key: username})
+	require.NoError(t, err)
+
+	loginAttempts, err = testQueries.ListLoginAttempts(context.Background(), ListLoginAttemptsParams{Username: username})
+	require.NoError(t, err)
+	require.Empty(t, loginAttempts)
+}
+
+func TestCreateAuditEvent(t *testing.T) {
+	arg := CreateAuditEventParams{
+		EventType: "login_lockout",
+		Username:  util.RandomOwner(),
+		ClientIp:  "10.0.0.1",
+		Details:   json.RawMessage(`{"kind":"username"}`),
+	}
+
+	auditEvent, err := testQueries.CreateAuditEvent(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, auditEvent.ID)
+	require.Equal(t, arg.EventType, auditEvent.EventType)
+	require.Equal(t, arg.Username, auditEvent.Username)
+	require.JSONEq(t, string(arg.Details), string(auditEvent.Details))
+	require.NotZero(t, auditEvent.CreatedAt)
+}
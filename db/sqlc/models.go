@@ -5,6 +5,8 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,6 +18,18 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// frozen accounts can not be debited or credited
+	IsFrozen bool `json:"is_frozen"`
+}
+
+type AuditEvent struct {
+	ID        int64  `json:"id"`
+	EventType string `json:"event_type"`
+	// not a foreign key, failed logins are audited for unknown usernames too
+	Username  string          `json:"username"`
+	ClientIp  string          `json:"client_ip"`
+	Details   json.RawMessage `json:"details"`
+	CreatedAt time.Time       `json:"created_at"`
 }
 
 type Entry struct {
@@ -26,6 +40,42 @@ type Entry struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type IdempotencyKey struct {
+	Username       string          `json:"username"`
+	IdempotencyKey string          `json:"idempotency_key"`
+	RequestHash    string          `json:"request_hash"`
+	Response       json.RawMessage `json:"response"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type LoginAttempt struct {
+	// username or client_ip
+	Kind         string    `json:"kind"`
+	Key          string    `json:"key"`
+	FailedCount  int32     `json:"failed_count"`
+	LastFailedAt time.Time `json:"last_failed_at"`
+	LockedUntil  time.Time `json:"locked_until"`
+}
+
+type PasswordReset struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 of the reset token, the token itself is only sent to the user
+	TokenHash string    `json:"token_hash"`
+	IsUsed    bool      `json:"is_used"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+type RecoveryCode struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// bcrypt hash of the recovery code, the code itself is only shown to the user once
+	HashedCode string    `json:"hashed_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 type Session struct {
 	ID           uuid.UUID `json:"id"`
 	Username     string    `json:"username"`
@@ -35,6 +85,25 @@ type Session struct {
 	IsBlocked    bool      `json:"is_blocked"`
 	ExpiresAt    time.Time `json:"expires_at"`
 	CreatedAt    time.Time `json:"created_at"`
+	// id of the login session the refresh token was rotated from
+	FamilyID uuid.UUID `json:"family_id"`
+	// set when the refresh token is exchanged for a new one
+	RotatedAt sql.NullTime `json:"rotated_at"`
+}
+
+type Task struct {
+	ID      int64           `json:"id"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+	// pending, running, done or failed
+	Status      string `json:"status"`
+	Attempts    int32  `json:"attempts"`
+	MaxAttempts int32  `json:"max_attempts"`
+	LastError   string `json:"last_error"`
+	// the task is not picked up by the worker before this time
+	RunAt     time.Time `json:"run_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type Transfer struct {
@@ -44,6 +113,16 @@ type Transfer struct {
 	// must be positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// rate applied to credit the amount in the destination account currency
+	ExchangeRate float64 `json:"exchange_rate"`
+}
+
+type UsedChallenge struct {
+	// payload ID of a two-factor challenge token, which can be exchanged for a session only once
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiredAt time.Time `json:"expired_at"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type User struct {
@@ -53,4 +132,22 @@ type User struct {
 	HashedPassword   string    `json:"hashed_password"`
 	PasswordChangeAt time.Time `json:"password_change_at"`
 	CreatedAt        time.Time `json:"created_at"`
+	// depositor, banker or admin
+	Role            string `json:"role"`
+	IsEmailVerified bool   `json:"is_email_verified"`
+	// base32 TOTP secret, set on enrollment and only used once is_totp_enabled is true
+	TotpSecret    string `json:"totp_secret"`
+	IsTotpEnabled bool   `json:"is_totp_enabled"`
+	// TOTP time step of the last accepted code, codes of this or an earlier step are rejected
+	TotpLastStep int64 `json:"totp_last_step"`
+}
+
+type VerifyEmail struct {
+	ID         int64     `json:"id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	SecretCode string    `json:"secret_code"`
+	IsUsed     bool      `json:"is_used"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiredAt  time.Time `json:"expired_at"`
 }
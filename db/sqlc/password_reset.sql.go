@@ -0,0 +1,106 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: password_reset.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createPasswordReset = `-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+    username,
+    token_hash,
+    expired_at
+) VALUES (
+    $1, $2, $3
+) RETURNING id, username, token_hash, is_used, created_at, expired_at
+`
+
+type CreatePasswordResetParams struct {
+	Username  string    `json:"username"`
+	TokenHash string    `json:"token_hash"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+func (q *Queries) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error) {
+	row := q.db.QueryRowContext(ctx, createPasswordReset, arg.Username, arg.TokenHash, arg.ExpiredAt)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.TokenHash,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
+
+const usePasswordReset = `-- name: UsePasswordReset :one
+UPDATE password_resets
+SET
+    is_used = TRUE
+WHERE
+    token_hash = $1
+    AND is_used = FALSE
+    AND expired_at > now()
+RETURNING id, username, token_hash, is_used, created_at, expired_at
+`
+
+func (q *Queries) UsePasswordReset(ctx context.Context, tokenHash string) (PasswordReset, error) {
+	row := q.db.QueryRowContext(ctx, usePasswordReset, tokenHash)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.TokenHash,
+		&i.IsUsed,
+		&i.CreatedAt,
+		&i.ExpiredAt,
+	)
+	return i, err
+}
+
+const useUserPasswordResets = `-- name: UseUserPasswordResets :many
+UPDATE password_resets
+SET
+    is_used = TRUE
+WHERE
+    username = $1
+    AND is_used = FALSE
+RETURNING id, username, token_hash, is_used, created_at, expired_at
+`
+
+func (q *Queries) UseUserPasswordResets(ctx context.Context, username string) ([]PasswordReset, error) {
+	rows, err := q.db.QueryContext(ctx, useUserPasswordResets, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PasswordReset{}
+	for rows.Next() {
+		var i PasswordReset
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.TokenHash,
+			&i.IsUsed,
+			&i.CreatedAt,
+			&i.ExpiredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -12,23 +12,65 @@ import (
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	BlockSession(ctx context.Context, id uuid.UUID) (Session, error)
+	BlockSessionFamily(ctx context.Context, familyID uuid.UUID) ([]Session, error)
+	BlockUserSession(ctx context.Context, arg BlockUserSessionParams) (Session, error)
+	BlockUserSessions(ctx context.Context, username string) ([]Session, error)
+	// running tasks not updated since stale_before are picked up again, so a crashed worker does not lose them
+	ClaimTasks(ctx context.Context, arg ClaimTasksParams) ([]Task, error)
+	CompleteTask(ctx context.Context, id int64) error
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateUsedChallenge(ctx context.Context, arg CreateUsedChallengeParams) (UsedChallenge, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteExpiredUsedChallenges(ctx context.Context) error
+	DeleteLoginAttempts(ctx context.Context, arg DeleteLoginAttemptsParams) error
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	FailTask(ctx context.Context, arg FailTaskParams) error
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountBalanceAt(ctx context.Context, arg GetAccountBalanceAtParams) (int64, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
 	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	ListAccountBalancesAfter(ctx context.Context, arg ListAccountBalancesAfterParams) ([]ListAccountBalancesAfterRow, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAccountsAfter(ctx context.Context, arg ListAccountsAfterParams) ([]Account, error)
+	ListActiveSessions(ctx context.Context, username string) ([]Session, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListEntriesAfter(ctx context.Context, arg ListEntriesAfterParams) ([]Entry, error)
+	ListEntriesBetween(ctx context.Context, arg ListEntriesBetweenParams) ([]Entry, error)
+	ListLoginAttempts(ctx context.Context, arg ListLoginAttemptsParams) ([]LoginAttempt, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListTransfersAfter(ctx context.Context, arg ListTransfersAfterParams) ([]Transfer, error)
+	ListUnusedRecoveryCodes(ctx context.Context, username string) ([]RecoveryCode, error)
+	// the count starts over when the last failure is older than reset_before
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginAttempt, error)
+	RetryTask(ctx context.Context, arg RetryTaskParams) error
+	RotateSession(ctx context.Context, id uuid.UUID) (Session, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountFrozen(ctx context.Context, arg UpdateAccountFrozenParams) (Account, error)
+	UpdateIdempotencyKeyResponse(ctx context.Context, arg UpdateIdempotencyKeyResponseParams) (IdempotencyKey, error)
+	UpdateLoginLockout(ctx context.Context, arg UpdateLoginLockoutParams) error
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateVerifyEmail(ctx context.Context, arg UpdateVerifyEmailParams) (VerifyEmail, error)
+	UsePasswordReset(ctx context.Context, tokenHash string) (PasswordReset, error)
+	UseRecoveryCode(ctx context.Context, id int64) (RecoveryCode, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (User, error)
+	UseUserPasswordResets(ctx context.Context, username string) ([]PasswordReset, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
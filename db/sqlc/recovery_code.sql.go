@@ -0,0 +1,107 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: recovery_code.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createRecoveryCode = `-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+    username,
+    hashed_code
+) VALUES (
+    $1, $2
+) RETURNING id, username, hashed_code, is_used, created_at
+`
+
+type CreateRecoveryCodeParams struct {
+	Username   string `json:"username"`
+	HashedCode string `json:"hashed_code"`
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error) {
+	row := q.db.QueryRowContext(ctx, createRecoveryCode, arg.Username, arg.HashedCode)
+	var i RecoveryCode
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedCode,
+		&i.IsUsed,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteRecoveryCodes, username)
+	return err
+}
+
+const listUnusedRecoveryCodes = `-- name: ListUnusedRecoveryCodes :many
+SELECT id, username, hashed_code, is_used, created_at FROM recovery_codes
+WHERE
+    username = $1
+    AND is_used = FALSE
+ORDER BY id
+`
+
+func (q *Queries) ListUnusedRecoveryCodes(ctx context.Context, username string) ([]RecoveryCode, error) {
+	rows, err := q.db.QueryContext(ctx, listUnusedRecoveryCodes, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RecoveryCode{}
+	for rows.Next() {
+		var i RecoveryCode
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.HashedCode,
+			&i.IsUsed,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+SET
+    is_used = TRUE
+WHERE
+    id = $1
+    AND is_used = FALSE
+RETURNING id, username, hashed_code, is_used, created_at
+`
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, id int64) (RecoveryCode, error) {
+	row := q.db.QueryRowContext(ctx, useRecoveryCode, id)
+	var i RecoveryCode
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.HashedCode,
+		&i.IsUsed,
+		&i.CreatedAt,
+	)
+	return i, err
+}
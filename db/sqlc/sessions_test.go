@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func createRandomSession(t *testing.T, username string) Session {
+	sessionID := uuid.New()
+
+	createSessionParams := CreateSessionParams{
+		ID:           sessionID,
+		Username:     username,
+		RefreshToken: util.RandomString(32),
+		UserAgent:    util.RandomString(10),
+		ClientIp:     "127.0.0.1",
+		IsBlocked:    false,
+		ExpiresAt:    time.Now().Add(time.Hour),
+		FamilyID:     sessionID,
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), createSessionParams)
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	require.Equal(t, createSessionParams.ID, session.ID)
+	require.Equal(t, createSessionParams.Username, session.Username)
+	require.Equal(t, createSessionParams.RefreshToken, session.RefreshToken)
+	require.Equal(t, createSessionParams.FamilyID, session.FamilyID)
+	require.False(t, session.IsBlocked)
+	require.False(t, session.RotatedAt.Valid)
+
+	return session
+}
+
+func TestListActiveSessions(t *testing.T) {
+	user := createRandomUser(t)
+
+	session1 := createRandomSession(t, user.Username)
+	session2 := createRandomSession(t, user.Username)
+
+	_, err := testQueries.BlockSession(context.Background(), session1.ID)
+	require.NoError(t, err)
+
+	sessions, err := testQueries.ListActiveSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, session2.ID, sessions[0].ID)
+}
+
+func TestBlockUserSession(t *testing.T) {
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+
+	session := createRandomSession(t, user1.Username)
+
+	// a session can only be blocked by its owner
+	_, err := testQueries.BlockUserSession(context.Background(), BlockUserSessionParams{
+		ID:       session.ID,
+		Username: user2.Username,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	blockedSession, err := testQueries.BlockUserSession(context.Background(), BlockUserSessionParams{
+		ID:       session.ID,
+		Username: user1.Username,
+	})
+	require.NoError(t, err)
+	require.True(t, blockedSession.IsBlocked)
+}
+
+func TestBlockUserSessions(t *testing.T) {
+	user := createRandomUser(t)
+
+	n := 3
+	for i := 0; i < n; i++ {
+		createRandomSession(t, user.Username)
+	}
+
+	blockedSessions, err := testQueries.BlockUserSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, blockedSessions, n)
+	for _, session := range blockedSessions {
+		require.True(t, session.IsBlocked)
+	}
+
+	sessions, err := testQueries.ListActiveSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
@@ -2,13 +2,45 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+var (
+	// ErrInsufficientFunds is returned when a transfer would overdraw the source account
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrIdempotencyKeyConflict is returned when an idempotency key is reused with different parameters
+	ErrIdempotencyKeyConflict = errors.New("idempotency key already used with different parameters")
+	// ErrInvalidExchangeRate is returned when a transfer is requested with a non positive exchange rate
+	ErrInvalidExchangeRate = errors.New("invalid exchange rate")
+	// ErrAccountFrozen is returned when the balance of a frozen account would change
+	ErrAccountFrozen = errors.New("account is frozen")
+	// ErrRefreshTokenReused is returned when the refresh token of an already rotated session is presented again
+	ErrRefreshTokenReused = errors.New("refresh token has already been used")
 )
 
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	ExchangeTransferTx(ctx context.Context, arg ExchangeTransferTxParams) (TransferTxResult, error)
+	AccountStatementTx(ctx context.Context, arg AccountStatementTxParams) (AccountStatementTxResult, error)
+	DepositTx(ctx context.Context, arg DepositTxParams) (DepositTxResult, error)
+	WithdrawTx(ctx context.Context, arg WithdrawTxParams) (WithdrawTxResult, error)
+	RotateSessionTx(ctx context.Context, arg RotateSessionTxParams) (RotateSessionTxResult, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error)
+	EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (EnableTOTPTxResult, error)
+	UseChallengeTx(ctx context.Context, arg UseChallengeTxParams) (UsedChallenge, error)
+	UseLoginCodeTx(ctx context.Context, arg UseLoginCodeTxParams) (UseLoginCodeTxResult, error)
 }
 
 type SQLStore struct {
@@ -26,7 +58,12 @@ func NewStore(db *sql.DB) Store {
 
 // execTx executes a transaction within a database transaction
 func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+	return store.execTxWithOptions(ctx, nil, fn)
+}
+
+// execTxWithOptions executes a function within a database transaction with the given isolation level and access mode
+func (store *SQLStore) execTxWithOptions(ctx context.Context, opts *sql.TxOptions, fn func(*Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
@@ -45,10 +82,13 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 }
 
 // TransferTxParams contains the input parameters of the transfer transaction
+// IdempotencyKey is optional, when set the transfer is executed at most once per Username and key
 type TransferTxParams struct {
-	FromAccountId int64 `json:"from_account_id"`
-	ToAccountId   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountId  int64  `json:"from_account_id"`
+	ToAccountId    int64  `json:"to_account_id"`
+	Amount         int64  `json:"amount"`
+	Username       string `json:"username"`
+	IdempotencyKey string `json:"idempotency_key"`
 }
 
 // TransferTxResult is the result of the transfer transaction
@@ -62,17 +102,50 @@ type TransferTxResult struct {
 
 // TransferTx performs money transfer from  one account to another
 // It creates transfer record, add account entries and update accounts balance within single database transaction
+// It returns ErrInsufficientFunds and rolls back if the source account balance would become negative
+// A repeated call with the same idempotency key returns the result of the first call
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+	return store.ExchangeTransferTx(ctx, ExchangeTransferTxParams{
+		TransferTxParams: arg,
+		ExchangeRate:     1,
+	})
+}
+
+// ExchangeTransferTxParams contains the input parameters of the transfer between accounts in different currencies
+// Amount is in the source account currency, ExchangeRate converts it to the destination account currency
+type ExchangeTransferTxParams struct {
+	TransferTxParams
+	ExchangeRate float64 `json:"exchange_rate"`
+}
+
+// ExchangeTransferTx performs money transfer between accounts in different currencies
+// It debits the amount from the source account and credits the converted amount to the destination account
+// The applied rate is recorded on the transfer
+func (store *SQLStore) ExchangeTransferTx(ctx context.Context, arg ExchangeTransferTxParams) (TransferTxResult, error) {
 
 	var result TransferTxResult
 
+	if arg.ExchangeRate <= 0 {
+		return result, ErrInvalidExchangeRate
+	}
+
+	toAmount := util.ConvertAmount(arg.Amount, arg.ExchangeRate)
+
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
 
+		if arg.IdempotencyKey != "" {
+			replayed, err := claimIdempotencyKey(ctx, q, arg.Username, arg.IdempotencyKey, transferTxRequestHash(arg.TransferTxParams), &result)
+			if err != nil || replayed {
+				return err
+			}
+		}
+
 		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
 			FromAccountID: arg.FromAccountId,
 			ToAccountID:   arg.ToAccountId,
 			Amount:        arg.Amount,
+			ExchangeRate:  arg.ExchangeRate,
 		})
 		if err != nil {
 			return err
@@ -88,7 +161,7 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 
 		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
 			AccountID: arg.ToAccountId,
-			Amount:    arg.Amount,
+			Amount:    toAmount,
 		})
 		if err != nil {
 			return err
@@ -96,9 +169,126 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 
 		// update accounts balance
 		if arg.FromAccountId < arg.ToAccountId {
-			result.FromAccount, result.ToAccount, err = AddMoney(ctx, q, arg.FromAccountId, -arg.Amount, arg.ToAccountId, arg.Amount)
+			result.FromAccount, result.ToAccount, err = AddMoney(ctx, q, arg.FromAccountId, -arg.Amount, arg.ToAccountId, toAmount)
 		} else {
-			result.FromAccount, result.ToAccount, err = AddMoney(ctx, q, arg.ToAccountId, arg.Amount, arg.FromAccountId, -arg.Amount)
+			result.ToAccount, result.FromAccount, err = AddMoney(ctx, q, arg.ToAccountId, toAmount, arg.FromAccountId, -arg.Amount)
+		}
+		if err != nil {
+			return err
+		}
+
+		// both rows are locked by the update above, so the checks are race free
+		if result.FromAccount.IsFrozen || result.ToAccount.IsFrozen {
+			return ErrAccountFrozen
+		}
+
+		if result.FromAccount.Balance < 0 {
+			return ErrInsufficientFunds
+		}
+
+		if arg.IdempotencyKey != "" {
+			return saveIdempotentResult(ctx, q, arg.Username, arg.IdempotencyKey, result)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// DepositTxParams contains the input parameters of the deposit transaction
+// IdempotencyKey is optional, when set the deposit is executed at most once per Username and key
+type DepositTxParams struct {
+	AccountID      int64  `json:"account_id"`
+	Amount         int64  `json:"amount"`
+	Username       string `json:"username"`
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+// DepositTxResult is the result of the deposit transaction
+type DepositTxResult struct {
+	Account Account `json:"account"`
+	Entry   Entry   `json:"entry"`
+}
+
+// DepositTx adds money to the account
+// It creates the account entry and updates the account balance within single database transaction
+// A repeated call with the same idempotency key returns the result of the first call
+func (store *SQLStore) DepositTx(ctx context.Context, arg DepositTxParams) (DepositTxResult, error) {
+
+	var result DepositTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		if arg.IdempotencyKey != "" {
+			requestHash := balanceChangeRequestHash("deposit", arg.AccountID, arg.Amount)
+			replayed, err := claimIdempotencyKey(ctx, q, arg.Username, arg.IdempotencyKey, requestHash, &result)
+			if err != nil || replayed {
+				return err
+			}
+		}
+
+		result.Account, result.Entry, err = addEntry(ctx, q, arg.AccountID, arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		if arg.IdempotencyKey != "" {
+			return saveIdempotentResult(ctx, q, arg.Username, arg.IdempotencyKey, result)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// WithdrawTxParams contains the input parameters of the withdraw transaction
+// IdempotencyKey is optional, when set the withdrawal is executed at most once per Username and key
+type WithdrawTxParams struct {
+	AccountID      int64  `json:"account_id"`
+	Amount         int64  `json:"amount"`
+	Username       string `json:"username"`
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+// WithdrawTxResult is the result of the withdraw transaction
+type WithdrawTxResult struct {
+	Account Account `json:"account"`
+	Entry   Entry   `json:"entry"`
+}
+
+// WithdrawTx takes money out of the account
+// It creates the account entry and updates the account balance within single database transaction
+// It returns ErrInsufficientFunds and rolls back if the account balance would become negative
+// A repeated call with the same idempotency key returns the result of the first call
+func (store *SQLStore) WithdrawTx(ctx context.Context, arg WithdrawTxParams) (WithdrawTxResult, error) {
+
+	var result WithdrawTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		if arg.IdempotencyKey != "" {
+			requestHash := balanceChangeRequestHash("withdraw", arg.AccountID, arg.Amount)
+			replayed, err := claimIdempotencyKey(ctx, q, arg.Username, arg.IdempotencyKey, requestHash, &result)
+			if err != nil || replayed {
+				return err
+			}
+		}
+
+		result.Account, result.Entry, err = addEntry(ctx, q, arg.AccountID, -arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		if result.Account.Balance < 0 {
+			return ErrInsufficientFunds
+		}
+
+		if arg.IdempotencyKey != "" {
+			return saveIdempotentResult(ctx, q, arg.Username, arg.IdempotencyKey, result)
 		}
 
 		return nil
@@ -107,6 +297,412 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 	return result, err
 }
 
+// addEntry records the entry of the account and adds its amount to the account balance
+func addEntry(ctx context.Context, q *Queries, accountID int64, amount int64) (account Account, entry Entry, err error) {
+	entry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: accountID,
+		Amount:    amount,
+	})
+	if err != nil {
+		return
+	}
+
+	account, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
+		ID:     accountID,
+		Amount: amount,
+	})
+	if err != nil {
+		return
+	}
+
+	if account.IsFrozen {
+		err = ErrAccountFrozen
+	}
+
+	return
+}
+
+// AccountStatementTxParams contains the input parameters of the account statement transaction
+type AccountStatementTxParams struct {
+	AccountID int64     `json:"account_id"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+}
+
+// StatementLine is an entry of the statement with the account balance after the entry
+type StatementLine struct {
+	Entry   Entry `json:"entry"`
+	Balance int64 `json:"balance"`
+}
+
+// AccountStatementTxResult is the result of the account statement transaction
+type AccountStatementTxResult struct {
+	OpeningBalance int64           `json:"opening_balance"`
+	ClosingBalance int64           `json:"closing_balance"`
+	Lines          []StatementLine `json:"lines"`
+}
+
+// AccountStatementTx builds the account statement for the [From, To) time range from the entries ledger
+// The opening balance is the current balance minus the entries since From, so it includes the balance
+// the account was created with, every line carries the running balance
+// Both queries read the same snapshot, so transfers committed in between cannot make them disagree
+func (store *SQLStore) AccountStatementTx(ctx context.Context, arg AccountStatementTxParams) (AccountStatementTxResult, error) {
+
+	var result AccountStatementTxResult
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+	err := store.execTxWithOptions(ctx, opts, func(q *Queries) error {
+		var err error
+
+		result.OpeningBalance, err = q.GetAccountBalanceAt(ctx, GetAccountBalanceAtParams{
+			AccountID: arg.AccountID,
+			AtTime:    arg.From,
+		})
+		if err != nil {
+			return err
+		}
+
+		entries, err := q.ListEntriesBetween(ctx, ListEntriesBetweenParams{
+			AccountID: arg.AccountID,
+			FromTime:  arg.From,
+			ToTime:    arg.To,
+		})
+		if err != nil {
+			return err
+		}
+
+		balance := result.OpeningBalance
+		result.Lines = make([]StatementLine, 0, len(entries))
+		for _, entry := range entries {
+			balance += entry.Amount
+			result.Lines = append(result.Lines, StatementLine{
+				Entry:   entry,
+				Balance: balance,
+			})
+		}
+		result.ClosingBalance = balance
+
+		return nil
+	})
+
+	return result, err
+}
+
+// RotateSessionTxParams contains the rotated session and the new session that replaces it
+type RotateSessionTxParams struct {
+	SessionID  uuid.UUID           `json:"session_id"`
+	NewSession CreateSessionParams `json:"new_session"`
+}
+
+// RotateSessionTxResult contains the new session
+// or the sessions of the family blocked because the refresh token was reused
+type RotateSessionTxResult struct {
+	Session         Session   `json:"session"`
+	BlockedSessions []Session `json:"blocked_sessions"`
+}
+
+// RotateSessionTx replaces the session with a new one of the same family
+// If the session was already rotated, the refresh token was reused, so the whole family is blocked
+// and ErrRefreshTokenReused is returned after the transaction is committed
+func (store *SQLStore) RotateSessionTx(ctx context.Context, arg RotateSessionTxParams) (RotateSessionTxResult, error) {
+
+	var result RotateSessionTxResult
+	var reused bool
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		session, err := q.RotateSession(ctx, arg.SessionID)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+
+			// the update only misses an existing session if it was already rotated
+			session, err = q.GetSession(ctx, arg.SessionID)
+			if err != nil {
+				return err
+			}
+
+			reused = true
+			result.BlockedSessions, err = q.BlockSessionFamily(ctx, session.FamilyID)
+			return err
+		}
+
+		newSession := arg.NewSession
+		newSession.FamilyID = session.FamilyID
+
+		result.Session, err = q.CreateSession(ctx, newSession)
+		return err
+	})
+	if err == nil && reused {
+		err = ErrRefreshTokenReused
+	}
+
+	return result, err
+}
+
+// CreateUserTxParams contains the new user and the tasks enqueued for it
+type CreateUserTxParams struct {
+	CreateUserParams
+	Tasks []CreateTaskParams `json:"tasks"`
+}
+
+// CreateUserTxResult contains the created user and the enqueued tasks
+type CreateUserTxResult struct {
+	User  User   `json:"user"`
+	Tasks []Task `json:"tasks"`
+}
+
+// CreateUserTx creates the user and enqueues its tasks in the same transaction
+// so the tasks never run for a user that was not created
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		for _, taskParams := range arg.Tasks {
+			task, err := q.CreateTask(ctx, taskParams)
+			if err != nil {
+				return err
+			}
+
+			result.Tasks = append(result.Tasks, task)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// UpdateUserTxParams contains the user changes and the tasks enqueued when the email changes
+type UpdateUserTxParams struct {
+	UpdateUserParams
+	EmailChangeTasks []CreateTaskParams `json:"email_change_tasks"`
+}
+
+// UpdateUserTxResult contains the updated user and the enqueued tasks
+type UpdateUserTxResult struct {
+	User  User   `json:"user"`
+	Tasks []Task `json:"tasks"`
+}
+
+// UpdateUserTx updates the user and, if the email changed, enqueues its tasks in the same transaction
+// so a new email address is never left without a verification email
+func (store *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error) {
+
+	var result UpdateUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		user, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUser(ctx, arg.UpdateUserParams)
+		if err != nil {
+			return err
+		}
+
+		if result.User.Email == user.Email {
+			return nil
+		}
+
+		for _, taskParams := range arg.EmailChangeTasks {
+			task, err := q.CreateTask(ctx, taskParams)
+			if err != nil {
+				return err
+			}
+
+			result.Tasks = append(result.Tasks, task)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// VerifyEmailTxParams contains the verify email record and its secret code
+type VerifyEmailTxParams struct {
+	EmailID    int64  `json:"email_id"`
+	SecretCode string `json:"secret_code"`
+}
+
+// VerifyEmailTxResult contains the verified user and the used verify email record
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// VerifyEmailTx marks the verify email record as used and the email of its user as verified
+// sql.ErrNoRows is returned if the code is wrong, expired, already used or the user has changed the email since
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+
+	var result VerifyEmailTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.VerifyEmail, err = q.UpdateVerifyEmail(ctx, UpdateVerifyEmailParams{
+			ID:         arg.EmailID,
+			SecretCode: arg.SecretCode,
+		})
+		if err != nil {
+			return err
+		}
+
+		// the code only verifies the email it was sent to, not an email set afterwards
+		result.User, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: result.VerifyEmail.Username,
+			Email:    result.VerifyEmail.Email,
+		})
+
+		return err
+	})
+
+	return result, err
+}
+
+// ResetPasswordTxParams contains the hash of the reset token and the new hashed password
+type ResetPasswordTxParams struct {
+	TokenHash      string `json:"token_hash"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+// ResetPasswordTxResult contains the updated user and the sessions blocked by the password change
+type ResetPasswordTxResult struct {
+	User            User      `json:"user"`
+	BlockedSessions []Session `json:"blocked_sessions"`
+}
+
+// ResetPasswordTx uses the reset token, changes the password and blocks all sessions of the user
+// The other reset tokens of the user are used up as well, so an older link can not change the password again
+// sql.ErrNoRows is returned if the token is unknown, expired or already used
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error) {
+
+	var result ResetPasswordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		passwordReset, err := q.UsePasswordReset(ctx, arg.TokenHash)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUser(ctx, UpdateUserParams{
+			Username:         passwordReset.Username,
+			HashedPassword:   sql.NullString{String: arg.HashedPassword, Valid: true},
+			PasswordChangeAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err := q.UseUserPasswordResets(ctx, passwordReset.Username); err != nil {
+			return err
+		}
+
+		result.BlockedSessions, err = q.BlockUserSessions(ctx, passwordReset.Username)
+		return err
+	})
+
+	return result, err
+}
+
+// EnableTOTPTxParams contains the user and the hashed recovery codes replacing the old ones
+// TOTPStep is the step of the confirmed code, which can not be used to login afterwards
+type EnableTOTPTxParams struct {
+	Username            string   `json:"username"`
+	HashedRecoveryCodes []string `json:"hashed_recovery_codes"`
+	TOTPStep            int64    `json:"totp_step"`
+}
+
+// EnableTOTPTxResult contains the updated user and the stored recovery codes
+type EnableTOTPTxResult struct {
+	User          User           `json:"user"`
+	RecoveryCodes []RecoveryCode `json:"recovery_codes"`
+}
+
+// EnableTOTPTx turns on two-factor authentication for the user
+// The recovery codes of a previous enrollment are deleted, so only the new ones can be used
+func (store *SQLStore) EnableTOTPTx(ctx context.Context, arg EnableTOTPTxParams) (EnableTOTPTxResult, error) {
+
+	var result EnableTOTPTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.UpdateUser(ctx, UpdateUserParams{
+			Username:      arg.Username,
+			IsTotpEnabled: sql.NullBool{Bool: true, Valid: true},
+			TotpLastStep:  sql.NullInt64{Int64: arg.TOTPStep, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := q.DeleteRecoveryCodes(ctx, arg.Username); err != nil {
+			return err
+		}
+
+		for _, hashedCode := range arg.HashedRecoveryCodes {
+			recoveryCode, err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username:   arg.Username,
+				HashedCode: hashedCode,
+			})
+			if err != nil {
+				return err
+			}
+
+			result.RecoveryCodes = append(result.RecoveryCodes, recoveryCode)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// UseChallengeTxParams contains the payload of the two-factor challenge token being exchanged for a session
+type UseChallengeTxParams struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// UseChallengeTx records the challenge token as used and drops the records of the expired challenge tokens
+// sql.ErrNoRows is returned if the challenge token has already been used
+func (store *SQLStore) UseChallengeTx(ctx context.Context, arg UseChallengeTxParams) (UsedChallenge, error) {
+
+	var result UsedChallenge
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		// expired challenge tokens are rejected by the token maker, their records are not needed anymore
+		if err := q.DeleteExpiredUsedChallenges(ctx); err != nil {
+			return err
+		}
+
+		result, err = q.CreateUsedChallenge(ctx, CreateUsedChallengeParams{
+			ID:        arg.ID,
+			Username:  arg.Username,
+			ExpiredAt: arg.ExpiredAt,
+		})
+
+		return err
+	})
+
+	return result, err
+}
+
 func AddMoney(ctx context.Context, q *Queries, accountID1 int64, amount1 int64, accountID2 int64, amount2 int64) (account1, account2 Account, err error) {
 
 	account1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
@@ -124,3 +720,132 @@ func AddMoney(ctx context.Context, q *Queries, accountID1 int64, amount1 int64,
 
 	return
 }
+
+// claimIdempotencyKey claims the idempotency key of the user for the request
+// If the key was already used it loads the stored response into result and reports the request as replayed
+// Concurrent requests with the same key block on the insert until the first transaction finishes
+func claimIdempotencyKey(ctx context.Context, q *Queries, username string, key string, requestHash string, result interface{}) (bool, error) {
+	_, err := q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+		Username:       username,
+		IdempotencyKey: key,
+		RequestHash:    requestHash,
+	})
+	if err == nil {
+		return false, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	idempotencyKey, err := q.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{
+		Username:       username,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if idempotencyKey.RequestHash != requestHash {
+		return false, ErrIdempotencyKeyConflict
+	}
+
+	return true, json.Unmarshal(idempotencyKey.Response, result)
+}
+
+// saveIdempotentResult stores the result of the request under its idempotency key
+func saveIdempotentResult(ctx context.Context, q *Queries, username string, key string, result interface{}) error {
+	response, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.UpdateIdempotencyKeyResponse(ctx, UpdateIdempotencyKeyResponseParams{
+		Username:       username,
+		IdempotencyKey: key,
+		Response:       response,
+	})
+
+	return err
+}
+
+// transferTxRequestHash fingerprints the transfer parameters bound to an idempotency key
+func transferTxRequestHash(arg TransferTxParams) string {
+	data := fmt.Sprintf("%d:%d:%d", arg.FromAccountId, arg.ToAccountId, arg.Amount)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(data)))
+}
+
+// balanceChangeRequestHash fingerprints the deposit or withdraw parameters bound to an idempotency key
+// The operation is part of the hash, so a key used for a deposit cannot replay a withdrawal
+func balanceChangeRequestHash(operation string, accountID int64, amount int64) string {
+	data := fmt.Sprintf("%s:%d:%d", operation, accountID, amount)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(data)))
+}
+
+// UseLoginCodeTxParams contains the user logging in and the TOTP or recovery code entered by the user
+type UseLoginCodeTxParams struct {
+	User User   `json:"user"`
+	Code string `json:"code"`
+}
+
+// UseLoginCodeTxResult contains the user with its last used TOTP step
+// and the used recovery code, if the code was one of them
+type UseLoginCodeTxResult struct {
+	User         User         `json:"user"`
+	RecoveryCode RecoveryCode `json:"recovery_code"`
+}
+
+// UseLoginCodeTx checks the TOTP code of the user, or the recovery codes if the code has their form
+// The accepted TOTP step or recovery code is recorded, so the code cannot be used again
+// sql.ErrNoRows is returned if the code is wrong or has already been used
+func (store *SQLStore) UseLoginCodeTx(ctx context.Context, arg UseLoginCodeTxParams) (UseLoginCodeTxResult, error) {
+
+	var result UseLoginCodeTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		if util.IsRecoveryCode(arg.Code) {
+			result.User = arg.User
+			result.RecoveryCode, err = useMatchingRecoveryCode(ctx, q, arg.User.Username, arg.Code)
+			return err
+		}
+
+		step, ok := util.CheckTOTPCode(arg.User.TotpSecret, arg.Code, time.Now())
+		if !ok || step <= arg.User.TotpLastStep {
+			return sql.ErrNoRows
+		}
+
+		// a concurrent login may have used this or a later code in the meantime, which returns sql.ErrNoRows
+		result.User, err = q.UseTOTPStep(ctx, UseTOTPStepParams{
+			Username: arg.User.Username,
+			Step:     step,
+		})
+
+		return err
+	})
+
+	return result, err
+}
+
+// useMatchingRecoveryCode marks the matching unused recovery code of the user as used
+// sql.ErrNoRows is returned if none of the codes match
+func useMatchingRecoveryCode(ctx context.Context, q *Queries, username string, code string) (RecoveryCode, error) {
+	recoveryCodes, err := q.ListUnusedRecoveryCodes(ctx, username)
+	if err != nil {
+		return RecoveryCode{}, err
+	}
+
+	code = util.NormalizeRecoveryCode(code)
+
+	for _, recoveryCode := range recoveryCodes {
+		if util.CheckPassword(code, recoveryCode.HashedCode) != nil {
+			continue
+		}
+
+		// a concurrent login may have used the code in the meantime, which returns sql.ErrNoRows
+		return q.UseRecoveryCode(ctx, recoveryCode.ID)
+	}
+
+	return RecoveryCode{}, sql.ErrNoRows
+}
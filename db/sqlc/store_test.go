@@ -2,15 +2,20 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"github.com/vladoohr/simple_bank/util"
 )
 
 func TestTransferTx(t *testing.T) {
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountWithBalance(t, util.RandomInt(100, 1000))
+	account2 := createRandomAccountWithBalance(t, util.RandomInt(100, 1000))
 	amount := int64(10)
 
 	fmt.Println("before >> ", account1.Balance, account2.Balance)
@@ -114,8 +119,8 @@ func TestTransferTx(t *testing.T) {
 }
 
 func TestTransferTxDeadLock(t *testing.T) {
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountWithBalance(t, util.RandomInt(100, 1000))
+	account2 := createRandomAccountWithBalance(t, util.RandomInt(100, 1000))
 	amount := int64(10)
 
 	fmt.Println("before >> ", account1.Balance, account2.Balance)
@@ -167,3 +172,651 @@ func TestTransferTxDeadLock(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	account1 := createRandomAccountWithBalance(t, 10)
+	account2 := createRandomAccount(t)
+
+	store := NewStore(testDB)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        account1.Balance + 1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// check that the transaction was rolled back
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestExchangeTransferTx(t *testing.T) {
+	account1 := createRandomAccountWithBalance(t, util.RandomInt(100, 1000))
+	account2 := createRandomAccountWithBalance(t, util.RandomInt(100, 1000))
+
+	store := NewStore(testDB)
+
+	amount := int64(10)
+	rate := 1.5
+
+	result, err := store.ExchangeTransferTx(context.Background(), ExchangeTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountId: account1.ID,
+			ToAccountId:   account2.ID,
+			Amount:        amount,
+		},
+		ExchangeRate: rate,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, amount, result.Transfer.Amount)
+	require.Equal(t, rate, result.Transfer.ExchangeRate)
+	require.Equal(t, -amount, result.FromEntry.Amount)
+	require.Equal(t, int64(15), result.ToEntry.Amount)
+	require.Equal(t, account1.Balance-amount, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+15, result.ToAccount.Balance)
+
+	_, err = store.ExchangeTransferTx(context.Background(), ExchangeTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountId: account1.ID,
+			ToAccountId:   account2.ID,
+			Amount:        amount,
+		},
+	})
+	require.ErrorIs(t, err, ErrInvalidExchangeRate)
+}
+
+func TestTransferTxFrozenAccount(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	store := NewStore(testDB)
+
+	frozenAccount, err := store.UpdateAccountFrozen(context.Background(), UpdateAccountFrozenParams{
+		ID:       account2.ID,
+		IsFrozen: true,
+	})
+	require.NoError(t, err)
+	require.True(t, frozenAccount.IsFrozen)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountId: account1.ID,
+		ToAccountId:   account2.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account2.ID,
+		Amount:    10,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	// check that the transactions were rolled back
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestTransferTxIdempotencyKey(t *testing.T) {
+	account1 := createRandomAccountWithBalance(t, util.RandomInt(100, 1000))
+	account2 := createRandomAccount(t)
+	amount := int64(10)
+
+	store := NewStore(testDB)
+
+	arg := TransferTxParams{
+		FromAccountId:  account1.ID,
+		ToAccountId:    account2.ID,
+		Amount:         amount,
+		Username:       account1.Owner,
+		IdempotencyKey: util.RandomString(16),
+	}
+
+	result1, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// replay returns the original result without moving the money again
+	result2, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, result1.Transfer.ID, result2.Transfer.ID)
+	require.Equal(t, result1.FromEntry.ID, result2.FromEntry.ID)
+	require.Equal(t, result1.ToEntry.ID, result2.ToEntry.ID)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-amount, updatedAccount1.Balance)
+
+	// same key with different parameters
+	arg.Amount = amount + 1
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyConflict)
+}
+
+func TestAccountStatementTx(t *testing.T) {
+	account := createRandomAccount(t)
+
+	store := NewStore(testDB)
+
+	amounts := []int64{100, -30, 50}
+	for _, amount := range amounts {
+		_, err := store.CreateEntry(context.Background(), CreateEntryParams{
+			AccountID: account.ID,
+			Amount:    amount,
+		})
+		require.NoError(t, err)
+
+		_, err = store.AddAccountBalance(context.Background(), AddAccountBalanceParams{
+			ID:     account.ID,
+			Amount: amount,
+		})
+		require.NoError(t, err)
+	}
+
+	result, err := store.AccountStatementTx(context.Background(), AccountStatementTxParams{
+		AccountID: account.ID,
+		From:      time.Now().Add(-time.Hour),
+		To:        time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, result.OpeningBalance)
+	require.Len(t, result.Lines, len(amounts))
+
+	balance := result.OpeningBalance
+	for i, line := range result.Lines {
+		balance += amounts[i]
+		require.Equal(t, account.ID, line.Entry.AccountID)
+		require.Equal(t, amounts[i], line.Entry.Amount)
+		require.Equal(t, balance, line.Balance)
+	}
+	require.Equal(t, balance, result.ClosingBalance)
+
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, updatedAccount.Balance, result.ClosingBalance)
+}
+
+func TestAccountStatementTxInitialBalance(t *testing.T) {
+	account := createRandomAccountWithBalance(t, 500)
+
+	store := NewStore(testDB)
+
+	depositResult, err := store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account.ID,
+		Amount:    20,
+	})
+	require.NoError(t, err)
+
+	// the statement starting after the deposit opens with the balance including the deposit
+	result, err := store.AccountStatementTx(context.Background(), AccountStatementTxParams{
+		AccountID: account.ID,
+		From:      time.Now().Add(time.Minute),
+		To:        time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, depositResult.Account.Balance, result.OpeningBalance)
+	require.Equal(t, depositResult.Account.Balance, result.ClosingBalance)
+	require.Empty(t, result.Lines)
+
+	// the statement covering the deposit opens with the balance the account was created with
+	result, err = store.AccountStatementTx(context.Background(), AccountStatementTxParams{
+		AccountID: account.ID,
+		From:      time.Now().Add(-time.Hour),
+		To:        time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(500), result.OpeningBalance)
+	require.Len(t, result.Lines, 1)
+	require.Equal(t, depositResult.Account.Balance, result.ClosingBalance)
+}
+
+func TestDepositAndWithdrawTx(t *testing.T) {
+	account := createRandomAccount(t)
+	amount := int64(10)
+
+	store := NewStore(testDB)
+
+	depositResult, err := store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account.ID,
+		Amount:    amount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+amount, depositResult.Account.Balance)
+	require.Equal(t, account.ID, depositResult.Entry.AccountID)
+	require.Equal(t, amount, depositResult.Entry.Amount)
+
+	withdrawResult, err := store.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID: account.ID,
+		Amount:    amount,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, withdrawResult.Account.Balance)
+	require.Equal(t, account.ID, withdrawResult.Entry.AccountID)
+	require.Equal(t, -amount, withdrawResult.Entry.Amount)
+
+	// withdraw more than the balance
+	_, err = store.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID: account.ID,
+		Amount:    account.Balance + 1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance, updatedAccount.Balance)
+}
+
+func TestDepositTxIdempotency(t *testing.T) {
+	account := createRandomAccount(t)
+	amount := int64(10)
+
+	store := NewStore(testDB)
+
+	arg := DepositTxParams{
+		AccountID:      account.ID,
+		Amount:         amount,
+		Username:       account.Owner,
+		IdempotencyKey: util.RandomString(16),
+	}
+
+	result1, err := store.DepositTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// the repeated deposit returns the first result without posting another entry
+	result2, err := store.DepositTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, result1.Entry.ID, result2.Entry.ID)
+	require.Equal(t, result1.Account.Balance, result2.Account.Balance)
+
+	updatedAccount, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account.Balance+amount, updatedAccount.Balance)
+
+	// the key cannot be reused for a withdrawal
+	_, err = store.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID:      account.ID,
+		Amount:         amount,
+		Username:       account.Owner,
+		IdempotencyKey: arg.IdempotencyKey,
+	})
+	require.ErrorIs(t, err, ErrIdempotencyKeyConflict)
+}
+
+func TestRotateSessionTx(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user.Username)
+
+	store := NewStore(testDB)
+
+	newSessionParams := func() CreateSessionParams {
+		return CreateSessionParams{
+			ID:           uuid.New(),
+			Username:     user.Username,
+			RefreshToken: util.RandomString(32),
+			UserAgent:    session.UserAgent,
+			ClientIp:     session.ClientIp,
+			ExpiresAt:    time.Now().Add(time.Hour),
+		}
+	}
+
+	result, err := store.RotateSessionTx(context.Background(), RotateSessionTxParams{
+		SessionID:  session.ID,
+		NewSession: newSessionParams(),
+	})
+	require.NoError(t, err)
+	require.NotEqual(t, session.ID, result.Session.ID)
+	require.Equal(t, session.FamilyID, result.Session.FamilyID)
+	require.Empty(t, result.BlockedSessions)
+
+	rotatedSession, err := store.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, rotatedSession.RotatedAt.Valid)
+
+	// presenting the rotated refresh token again blocks the whole family
+	result, err = store.RotateSessionTx(context.Background(), RotateSessionTxParams{
+		SessionID:  session.ID,
+		NewSession: newSessionParams(),
+	})
+	require.ErrorIs(t, err, ErrRefreshTokenReused)
+	require.Len(t, result.BlockedSessions, 2)
+
+	sessions, err := store.ListActiveSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
+func TestCreateUserTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomOwner(),
+			HashedPassword: hashedPassword,
+			FullName:       util.RandomOwner(),
+			Email:          util.RandomEmail(),
+		},
+		Tasks: []CreateTaskParams{
+			{
+				Type:        "task:test",
+				Payload:     []byte(`{"username":"test"}`),
+				MaxAttempts: 3,
+				RunAt:       time.Now(),
+			},
+		},
+	}
+
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, result.User.Username)
+	require.False(t, result.User.IsEmailVerified)
+
+	require.Len(t, result.Tasks, 1)
+	require.NotZero(t, result.Tasks[0].ID)
+	require.Equal(t, "task:test", result.Tasks[0].Type)
+	require.Equal(t, "pending", result.Tasks[0].Status)
+	require.JSONEq(t, `{"username":"test"}`, string(result.Tasks[0].Payload))
+
+	// a duplicate user rolls back its tasks
+	arg.Email = util.RandomEmail()
+	_, err = store.CreateUserTx(context.Background(), arg)
+	require.Error(t, err)
+}
+
+func TestUpdateUserTx(t *testing.T) {
+	user := createRandomUser(t)
+	store := NewStore(testDB)
+
+	task := CreateTaskParams{
+		Type:        "task:test",
+		Payload:     []byte(`{"username":"test"}`),
+		MaxAttempts: 3,
+		RunAt:       time.Now(),
+	}
+
+	// the same email does not enqueue the tasks
+	result, err := store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: user.Username,
+			Email:    sql.NullString{String: user.Email, Valid: true},
+		},
+		EmailChangeTasks: []CreateTaskParams{task},
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Email, result.User.Email)
+	require.Empty(t, result.Tasks)
+
+	newEmail := util.RandomEmail()
+	result, err = store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: user.Username,
+			Email:    sql.NullString{String: newEmail, Valid: true},
+		},
+		EmailChangeTasks: []CreateTaskParams{task},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newEmail, result.User.Email)
+	require.False(t, result.User.IsEmailVerified)
+
+	require.Len(t, result.Tasks, 1)
+	require.NotZero(t, result.Tasks[0].ID)
+	require.Equal(t, "task:test", result.Tasks[0].Type)
+
+	_, err = store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{Username: util.RandomOwner()},
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	user := createRandomUser(t)
+	store := NewStore(testDB)
+
+	verifyEmail, err := store.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+	})
+	require.NoError(t, err)
+	require.False(t, verifyEmail.IsUsed)
+
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: util.RandomString(32),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	result, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.NoError(t, err)
+	require.True(t, result.VerifyEmail.IsUsed)
+	require.True(t, result.User.IsEmailVerified)
+
+	// the code can be used only once
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestVerifyEmailTxEmailChanged(t *testing.T) {
+	user := createRandomUser(t)
+	store := NewStore(testDB)
+
+	verifyEmail, err := store.CreateVerifyEmail(context.Background(), CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: util.RandomString(32),
+	})
+	require.NoError(t, err)
+
+	_, err = store.UpdateUser(context.Background(), UpdateUserParams{
+		Username: user.Username,
+		Email:    sql.NullString{String: util.RandomEmail(), Valid: true},
+	})
+	require.NoError(t, err)
+
+	// the code sent to the previous email does not verify the new one
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:    verifyEmail.ID,
+		SecretCode: verifyEmail.SecretCode,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	updatedUser, err := store.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.False(t, updatedUser.IsEmailVerified)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user.Username)
+	store := NewStore(testDB)
+
+	tokenHash := util.HashToken(util.RandomString(32))
+	_, err := store.CreatePasswordReset(context.Background(), CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: tokenHash,
+		ExpiredAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	// the user requested a reset twice
+	otherTokenHash := util.HashToken(util.RandomString(32))
+	_, err = store.CreatePasswordReset(context.Background(), CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: otherTokenHash,
+		ExpiredAt: time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := ResetPasswordTxParams{
+		TokenHash:      tokenHash,
+		HashedPassword: hashedPassword,
+	}
+
+	result, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.WithinDuration(t, time.Now(), result.User.PasswordChangeAt, 5*time.Second)
+
+	require.Len(t, result.BlockedSessions, 1)
+	require.Equal(t, session.ID, result.BlockedSessions[0].ID)
+	require.True(t, result.BlockedSessions[0].IsBlocked)
+
+	// the token can be used only once
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// the other token of the user was used up by the reset
+	arg.TokenHash = otherTokenHash
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestEnableTOTPTx(t *testing.T) {
+	user := createRandomUser(t)
+	store := NewStore(testDB)
+
+	oldCode, err := store.CreateRecoveryCode(context.Background(), CreateRecoveryCodeParams{
+		Username:   user.Username,
+		HashedCode: util.RandomString(60),
+	})
+	require.NoError(t, err)
+
+	arg := EnableTOTPTxParams{
+		Username:            user.Username,
+		HashedRecoveryCodes: []string{util.RandomString(60), util.RandomString(60)},
+		TOTPStep:            util.TOTPStep(time.Now()),
+	}
+
+	result, err := store.EnableTOTPTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.User.IsTotpEnabled)
+	require.Equal(t, arg.TOTPStep, result.User.TotpLastStep)
+	require.Len(t, result.RecoveryCodes, 2)
+
+	// the code used to confirm the enrollment cannot be used to login
+	_, err = store.UseTOTPStep(context.Background(), UseTOTPStepParams{
+		Username: user.Username,
+		Step:     arg.TOTPStep,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	updatedUser, err := store.UseTOTPStep(context.Background(), UseTOTPStepParams{
+		Username: user.Username,
+		Step:     arg.TOTPStep + 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, arg.TOTPStep+1, updatedUser.TotpLastStep)
+
+	recoveryCodes, err := store.ListUnusedRecoveryCodes(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, 2)
+
+	for i, recoveryCode := range recoveryCodes {
+		require.NotEqual(t, oldCode.ID, recoveryCode.ID)
+		require.Equal(t, arg.HashedRecoveryCodes[i], recoveryCode.HashedCode)
+	}
+
+	// a recovery code can be used only once
+	usedCode, err := store.UseRecoveryCode(context.Background(), recoveryCodes[0].ID)
+	require.NoError(t, err)
+	require.True(t, usedCode.IsUsed)
+
+	_, err = store.UseRecoveryCode(context.Background(), recoveryCodes[0].ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUseChallengeTx(t *testing.T) {
+	user := createRandomUser(t)
+	store := NewStore(testDB)
+
+	arg := UseChallengeTxParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiredAt: time.Now().Add(time.Minute),
+	}
+
+	usedChallenge, err := store.UseChallengeTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, usedChallenge.ID)
+	require.Equal(t, user.Username, usedChallenge.Username)
+
+	// a challenge token can be exchanged for a session only once
+	_, err = store.UseChallengeTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUseLoginCodeTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	secret, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	user := createRandomUser(t)
+	user, err = store.UpdateUser(context.Background(), UpdateUserParams{
+		Username:      user.Username,
+		TotpSecret:    sql.NullString{String: secret, Valid: true},
+		IsTotpEnabled: sql.NullBool{Bool: true, Valid: true},
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := util.GenerateTOTPCode(secret, now)
+	require.NoError(t, err)
+
+	result, err := store.UseLoginCodeTx(context.Background(), UseLoginCodeTxParams{User: user, Code: code})
+	require.NoError(t, err)
+	require.Equal(t, util.TOTPStep(now), result.User.TotpLastStep)
+
+	// the code cannot be used again, neither after reading the user again nor by a concurrent login
+	_, err = store.UseLoginCodeTx(context.Background(), UseLoginCodeTxParams{User: result.User, Code: code})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.UseLoginCodeTx(context.Background(), UseLoginCodeTxParams{User: user, Code: code})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.UseLoginCodeTx(context.Background(), UseLoginCodeTxParams{User: user, Code: "wrong"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	recoveryCodes, err := util.GenerateRecoveryCodes(1)
+	require.NoError(t, err)
+
+	hashedCode, err := util.HashPassword(recoveryCodes[0])
+	require.NoError(t, err)
+
+	recoveryCode, err := store.CreateRecoveryCode(context.Background(), CreateRecoveryCodeParams{
+		Username:   user.Username,
+		HashedCode: hashedCode,
+	})
+	require.NoError(t, err)
+
+	// recovery codes are accepted however the user types them
+	typedCode := " " + strings.ToUpper(recoveryCodes[0]) + " "
+	result, err = store.UseLoginCodeTx(context.Background(), UseLoginCodeTxParams{User: user, Code: typedCode})
+	require.NoError(t, err)
+	require.Equal(t, recoveryCode.ID, result.RecoveryCode.ID)
+	require.True(t, result.RecoveryCode.IsUsed)
+
+	_, err = store.UseLoginCodeTx(context.Background(), UseLoginCodeTxParams{User: user, Code: recoveryCodes[0]})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,164 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: task.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const claimTasks = `-- name: ClaimTasks :many
+UPDATE tasks
+SET
+    status = 'running',
+    attempts = attempts + 1,
+    updated_at = now()
+WHERE id IN (
+    SELECT t.id FROM tasks t
+    WHERE
+        (t.status = 'pending' AND t.run_at <= now())
+        OR (t.status = 'running' AND t.updated_at < $1)
+    ORDER BY t.run_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, type, payload, status, attempts, max_attempts, last_error, run_at, created_at, updated_at
+`
+
+type ClaimTasksParams struct {
+	StaleBefore time.Time `json:"stale_before"`
+	BatchSize   int32     `json:"batch_size"`
+}
+
+// running tasks not updated since stale_before are picked up again, so a crashed worker does not lose them
+func (q *Queries) ClaimTasks(ctx context.Context, arg ClaimTasksParams) ([]Task, error) {
+	rows, err := q.db.QueryContext(ctx, claimTasks, arg.StaleBefore, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Task{}
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.MaxAttempts,
+			&i.LastError,
+			&i.RunAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeTask = `-- name: CompleteTask :exec
+UPDATE tasks
+SET
+    status = 'done',
+    updated_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) CompleteTask(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, completeTask, id)
+	return err
+}
+
+const createTask = `-- name: CreateTask :one
+INSERT INTO tasks (
+    type,
+    payload,
+    max_attempts,
+    run_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, type, payload, status, attempts, max_attempts, last_error, run_at, created_at, updated_at
+`
+
+type CreateTaskParams struct {
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	MaxAttempts int32           `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+}
+
+func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error) {
+	row := q.db.QueryRowContext(ctx, createTask,
+		arg.Type,
+		arg.Payload,
+		arg.MaxAttempts,
+		arg.RunAt,
+	)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.Type,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.LastError,
+		&i.RunAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const failTask = `-- name: FailTask :exec
+UPDATE tasks
+SET
+    status = 'failed',
+    last_error = $2,
+    updated_at = now()
+WHERE id = $1
+`
+
+type FailTaskParams struct {
+	ID        int64  `json:"id"`
+	LastError string `json:"last_error"`
+}
+
+func (q *Queries) FailTask(ctx context.Context, arg FailTaskParams) error {
+	_, err := q.db.ExecContext(ctx, failTask, arg.ID, arg.LastError)
+	return err
+}
+
+const retryTask = `-- name: RetryTask :exec
+UPDATE tasks
+SET
+    status = 'pending',
+    last_error = $2,
+    run_at = $3,
+    updated_at = now()
+WHERE id = $1
+`
+
+type RetryTaskParams struct {
+	ID        int64     `json:"id"`
+	LastError string    `json:"last_error"`
+	RunAt     time.Time `json:"run_at"`
+}
+
+func (q *Queries) RetryTask(ctx context.Context, arg RetryTaskParams) error {
+	_, err := q.db.ExecContext(ctx, retryTask, arg.ID, arg.LastError, arg.RunAt)
+	return err
+}
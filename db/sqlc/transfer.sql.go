@@ -13,20 +13,27 @@ const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  exchange_rate
 ) VALUES (
-  $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+  $1, $2, $3, $4
+) RETURNING id, from_account_id, to_account_id, amount, created_at, exchange_rate
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64   `json:"from_account_id"`
+	ToAccountID   int64   `json:"to_account_id"`
+	Amount        int64   `json:"amount"`
+	ExchangeRate  float64 `json:"exchange_rate"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ExchangeRate,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -34,12 +41,13 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ExchangeRate,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, exchange_rate FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -52,12 +60,13 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ExchangeRate,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, exchange_rate FROM transfers
 WHERE 
     from_account_id = $1 OR
     to_account_id = $2
@@ -93,6 +102,59 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ExchangeRate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransfersAfter = `-- name: ListTransfersAfter :many
+SELECT id, from_account_id, to_account_id, amount, created_at, exchange_rate FROM transfers
+WHERE 
+    (from_account_id = $1 OR
+    to_account_id = $2) AND
+    id > $3
+ORDER BY id
+LIMIT $4
+`
+
+type ListTransfersAfterParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	AfterID       int64 `json:"after_id"`
+	LimitSize     int32 `json:"limit_size"`
+}
+
+func (q *Queries) ListTransfersAfter(ctx context.Context, arg ListTransfersAfterParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listTransfersAfter,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.AfterID,
+		arg.LimitSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ExchangeRate,
 		); err != nil {
 			return nil, err
 		}
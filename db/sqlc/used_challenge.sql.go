@@ -0,0 +1,53 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.15.0
+// source: used_challenge.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createUsedChallenge = `-- name: CreateUsedChallenge :one
+INSERT INTO used_challenges (
+    id,
+    username,
+    expired_at
+) VALUES (
+    $1, $2, $3
+)
+ON CONFLICT (id) DO NOTHING
+RETURNING id, username, expired_at, created_at
+`
+
+type CreateUsedChallengeParams struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+func (q *Queries) CreateUsedChallenge(ctx context.Context, arg CreateUsedChallengeParams) (UsedChallenge, error) {
+	row := q.db.QueryRowContext(ctx, createUsedChallenge, arg.ID, arg.Username, arg.ExpiredAt)
+	var i UsedChallenge
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.ExpiredAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteExpiredUsedChallenges = `-- name: DeleteExpiredUsedChallenges :exec
+DELETE FROM used_challenges
+WHERE expired_at < now()
+`
+
+func (q *Queries) DeleteExpiredUsedChallenges(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredUsedChallenges)
+	return err
+}
@@ -18,7 +18,7 @@ INSERT INTO users (
     hashed_password
 ) VALUES (
     $1, $2, $3, $4
-) RETURNING username, email, full_name, hashed_password, password_change_at, created_at
+) RETURNING username, email, full_name, hashed_password, password_change_at, created_at, role, is_email_verified, totp_secret, is_totp_enabled, totp_last_step
 `
 
 type CreateUserParams struct {
@@ -43,12 +43,17 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.HashedPassword,
 		&i.PasswordChangeAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, email, full_name, hashed_password, password_change_at, created_at FROM users
+SELECT username, email, full_name, hashed_password, password_change_at, created_at, role, is_email_verified, totp_secret, is_totp_enabled, totp_last_step FROM users
 WHERE username = $1
 LIMIT 1
 `
@@ -63,6 +68,36 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.HashedPassword,
 		&i.PasswordChangeAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, email, full_name, hashed_password, password_change_at, created_at, role, is_email_verified, totp_secret, is_totp_enabled, totp_last_step FROM users
+WHERE email = $1
+LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.Email,
+		&i.FullName,
+		&i.HashedPassword,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
@@ -73,10 +108,17 @@ SET
     hashed_password = COALESCE($1, hashed_password),
     password_change_at = COALESCE($2, password_change_at),
     email = COALESCE($3, email),
-    full_name = COALESCE($4, full_name)
+    full_name = COALESCE($4, full_name),
+    is_email_verified = CASE
+        WHEN $3 IS NOT NULL AND $3 <> email THEN FALSE
+        ELSE COALESCE($5, is_email_verified)
+    END,
+    totp_secret = COALESCE($6, totp_secret),
+    is_totp_enabled = COALESCE($7, is_totp_enabled),
+    totp_last_step = COALESCE($8, totp_last_step)
 WHERE 
-    username = $5
-RETURNING username, email, full_name, hashed_password, password_change_at, created_at
+    username = $9
+RETURNING username, email, full_name, hashed_password, password_change_at, created_at, role, is_email_verified, totp_secret, is_totp_enabled, totp_last_step
 `
 
 type UpdateUserParams struct {
@@ -84,6 +126,10 @@ type UpdateUserParams struct {
 	PasswordChangeAt sql.NullTime   `json:"password_change_at"`
 	Email            sql.NullString `json:"email"`
 	FullName         sql.NullString `json:"full_name"`
+	IsEmailVerified  sql.NullBool   `json:"is_email_verified"`
+	TotpSecret       sql.NullString `json:"totp_secret"`
+	IsTotpEnabled    sql.NullBool   `json:"is_totp_enabled"`
+	TotpLastStep     sql.NullInt64  `json:"totp_last_step"`
 	Username         string         `json:"username"`
 }
 
@@ -93,6 +139,10 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		arg.PasswordChangeAt,
 		arg.Email,
 		arg.FullName,
+		arg.IsEmailVerified,
+		arg.TotpSecret,
+		arg.IsTotpEnabled,
+		arg.TotpLastStep,
 		arg.Username,
 	)
 	var i User
@@ -103,6 +153,79 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.HashedPassword,
 		&i.PasswordChangeAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :one
+UPDATE users
+SET
+    totp_last_step = $1
+WHERE
+    username = $2
+    AND totp_last_step < $1
+RETURNING username, email, full_name, hashed_password, password_change_at, created_at, role, is_email_verified, totp_secret, is_totp_enabled, totp_last_step
+`
+
+type UseTOTPStepParams struct {
+	Step     int64  `json:"step"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, useTOTPStep, arg.Step, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.Email,
+		&i.FullName,
+		&i.HashedPassword,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET
+    is_email_verified = TRUE
+WHERE
+    username = $1
+    AND email = $2
+RETURNING username, email, full_name, hashed_password, password_change_at, created_at, role, is_email_verified, totp_secret, is_totp_enabled, totp_last_step
+`
+
+type VerifyUserEmailParams struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.Username, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.Email,
+		&i.FullName,
+		&i.HashedPassword,
+		&i.PasswordChangeAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
@@ -30,6 +30,7 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, createUserParams.HashedPassword, user.HashedPassword)
 	require.Equal(t, createUserParams.FullName, user.FullName)
 	require.Equal(t, createUserParams.Email, user.Email)
+	require.Equal(t, util.DepositorRole, user.Role)
 
 	require.NotZero(t, user.CreatedAt)
 	require.NotZero(t, user.PasswordChangeAt)
@@ -76,3 +77,32 @@ func TestUpdateFullnameOnly(t *testing.T) {
 	require.Equal(t, oldUser.Email, user.Email)
 	require.Equal(t, oldUser.HashedPassword, user.HashedPassword)
 }
+
+func TestUpdateEmailResetsVerification(t *testing.T) {
+	oldUser := createRandomUser(t)
+
+	verifiedUser, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username:        oldUser.Username,
+		IsEmailVerified: sql.NullBool{Bool: true, Valid: true},
+	})
+	require.NoError(t, err)
+	require.True(t, verifiedUser.IsEmailVerified)
+
+	// setting the same email keeps the verification
+	user, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username: oldUser.Username,
+		Email:    sql.NullString{String: oldUser.Email, Valid: true},
+	})
+	require.NoError(t, err)
+	require.True(t, user.IsEmailVerified)
+
+	newEmail := util.RandomEmail()
+
+	user, err = testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username: oldUser.Username,
+		Email:    sql.NullString{String: newEmail, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newEmail, user.Email)
+	require.False(t, user.IsEmailVerified)
+}
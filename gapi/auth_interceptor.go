@@ -0,0 +1,126 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vladoohr/simple_bank/token"
+	"github.com/vladoohr/simple_bank/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// methodPolicy describes how the calls of a gRPC method are authorized
+type methodPolicy struct {
+	// public methods can be called without an access token
+	public bool
+	// roles allowed to call the method, any authenticated user is allowed if empty
+	roles []string
+}
+
+// methodPolicies lists the policies of all gRPC methods
+// Methods missing from the table still require an authenticated user
+var methodPolicies = map[string]methodPolicy{
+	"/pb.SimpleBank/CreateUser":           {public: true},
+	"/pb.SimpleBank/LoginUser":            {public: true},
+	"/pb.SimpleBank/RenewAccessToken":     {public: true},
+	"/pb.SimpleBank/VerifyEmail":          {public: true},
+	"/pb.SimpleBank/RequestPasswordReset": {public: true},
+	"/pb.SimpleBank/ResetPassword":        {public: true},
+	"/pb.SimpleBank/LoginUserTOTP":        {public: true},
+
+	"/pb.SimpleBank/UpdateUser":          {},
+	"/pb.SimpleBank/CreateAccount":       {},
+	"/pb.SimpleBank/GetAccount":          {},
+	"/pb.SimpleBank/ListAccounts":        {},
+	"/pb.SimpleBank/CreateTransfer":      {},
+	"/pb.SimpleBank/ListEntries":         {},
+	"/pb.SimpleBank/ListTransfers":       {},
+	"/pb.SimpleBank/GetAccountStatement": {},
+	"/pb.SimpleBank/Withdraw":            {},
+	"/pb.SimpleBank/ListSessions":        {},
+	"/pb.SimpleBank/RevokeSession":       {},
+	"/pb.SimpleBank/RevokeAllSessions":   {},
+	"/pb.SimpleBank/Logout":              {},
+	"/pb.SimpleBank/EnrollTOTP":          {},
+	"/pb.SimpleBank/ConfirmTOTP":         {},
+
+	// deposits are made by the bank staff, the account owners can only withdraw their money
+	"/pb.SimpleBank/Deposit": {roles: []string{util.BankerRole, util.AdminRole}},
+
+	"/pb.SimpleBank/FreezeAccount": {roles: []string{util.AdminRole}},
+	"/pb.SimpleBank/BlockSession":  {roles: []string{util.AdminRole}},
+}
+
+// policyForMethod returns the policy of the method or the default policy that requires authentication
+func policyForMethod(fullMethod string) methodPolicy {
+	if policy, ok := methodPolicies[fullMethod]; ok {
+		return policy
+	}
+
+	return methodPolicy{}
+}
+
+// allows returns true if the role is allowed to call the method
+func (policy methodPolicy) allows(role string) bool {
+	if len(policy.roles) == 0 {
+		return true
+	}
+
+	for _, r := range policy.roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+type authPayloadContextKey struct{}
+
+// AuthInterceptor is a unary interceptor that rejects unauthenticated calls to protected methods
+// and stores the access token payload of the authenticated user in the request context
+func (server *Server) AuthInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	policy := policyForMethod(info.FullMethod)
+	if policy.public {
+		return handler(ctx, req)
+	}
+
+	authPayload, err := server.authorizeUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	setLogUsername(ctx, authPayload.Username)
+
+	revoked, err := server.denylist.Contains(ctx, authPayload.SessionID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check session: %s", err)
+	}
+
+	if revoked {
+		return nil, unauthenticatedError(errors.New("session has been revoked"))
+	}
+
+	if !policy.allows(authPayload.Role) {
+		return nil, status.Errorf(codes.PermissionDenied, "role %s is not allowed to call %s", authPayload.Role, info.FullMethod)
+	}
+
+	return handler(context.WithValue(ctx, authPayloadContextKey{}, authPayload), req)
+}
+
+// authPayloadFromContext returns the access token payload stored by the AuthInterceptor
+func authPayloadFromContext(ctx context.Context) (*token.Payload, error) {
+	authPayload, ok := ctx.Value(authPayloadContextKey{}).(*token.Payload)
+	if !ok || authPayload == nil {
+		return nil, errors.New("missing authenticated user")
+	}
+
+	return authPayload, nil
+}
@@ -0,0 +1,323 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/token"
+	"github.com/vladoohr/simple_bank/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newContextWithToken returns an incoming context carrying a bearer access token of the user,
+// the way a client calls the server
+func newContextWithToken(t *testing.T, tokenMaker token.Maker, username string, role string, sessionID uuid.UUID) context.Context {
+	accessToken, _, err := tokenMaker.CreateToken(username, role, sessionID, time.Minute)
+	require.NoError(t, err)
+
+	md := metadata.Pairs(authorizationHeader, fmt.Sprintf("%s %s", authorizationBearer, accessToken))
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestAuthInterceptorPublicMethods(t *testing.T) {
+	for fullMethod, policy := range methodPolicies {
+		if !policy.public {
+			continue
+		}
+
+		t.Run(fullMethod, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// public methods must not touch the store before the handler runs
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			called := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return nil, nil
+			}
+
+			info := &grpc.UnaryServerInfo{FullMethod: fullMethod}
+			_, err := server.AuthInterceptor(context.Background(), nil, info, handler)
+			require.NoError(t, err)
+			require.True(t, called)
+		})
+	}
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	user := randomUser(t)
+	sessionID := uuid.New()
+
+	testCases := []struct {
+		name          string
+		fullMethod    string
+		buildStubs    func(store *mockdb.MockStore)
+		buildContext  func(t *testing.T, server *Server) context.Context
+		checkResponse func(t *testing.T, payload *token.Payload, err error)
+	}{
+		{
+			name:       "OK",
+			fullMethod: "/pb.SimpleBank/GetAccount",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithToken(t, server.tokenMaker, user.Username, util.DepositorRole, sessionID)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+				require.Equal(t, sessionID, payload.SessionID)
+			},
+		},
+		{
+			name:       "MissingToken",
+			fullMethod: "/pb.SimpleBank/GetAccount",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return metadata.NewIncomingContext(context.Background(), metadata.MD{})
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:       "MissingMetadata",
+			fullMethod: "/pb.SimpleBank/GetAccount",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:       "InvalidToken",
+			fullMethod: "/pb.SimpleBank/GetAccount",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				md := metadata.Pairs(authorizationHeader, authorizationBearer+" invalid")
+				return metadata.NewIncomingContext(context.Background(), md)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:       "UnsupportedAuthorizationType",
+			fullMethod: "/pb.SimpleBank/GetAccount",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				accessToken, _, err := server.tokenMaker.CreateToken(user.Username, util.DepositorRole, sessionID, time.Minute)
+				require.NoError(t, err)
+
+				md := metadata.Pairs(authorizationHeader, "basic "+accessToken)
+				return metadata.NewIncomingContext(context.Background(), md)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:       "UserNotFound",
+			fullMethod: "/pb.SimpleBank/GetAccount",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithToken(t, server.tokenMaker, user.Username, util.DepositorRole, sessionID)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:       "IssuedBeforePasswordChange",
+			fullMethod: "/pb.SimpleBank/GetAccount",
+			buildStubs: func(store *mockdb.MockStore) {
+				changedUser := user
+				changedUser.PasswordChangeAt = time.Now().Add(time.Minute)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(changedUser, nil)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithToken(t, server.tokenMaker, user.Username, util.DepositorRole, sessionID)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:       "DeniedSession",
+			fullMethod: "/pb.SimpleBank/GetAccount",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				err := server.denylist.Add(context.Background(), sessionID, time.Now().Add(time.Hour))
+				require.NoError(t, err)
+
+				return newContextWithToken(t, server.tokenMaker, user.Username, util.DepositorRole, sessionID)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:       "DepositorDeposit",
+			fullMethod: "/pb.SimpleBank/Deposit",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithToken(t, server.tokenMaker, user.Username, util.DepositorRole, sessionID)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:       "DepositorFreezeAccount",
+			fullMethod: "/pb.SimpleBank/FreezeAccount",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithToken(t, server.tokenMaker, user.Username, util.DepositorRole, sessionID)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:       "DepositorBlockSession",
+			fullMethod: "/pb.SimpleBank/BlockSession",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithToken(t, server.tokenMaker, user.Username, util.DepositorRole, sessionID)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:       "BankerDeposit",
+			fullMethod: "/pb.SimpleBank/Deposit",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithToken(t, server.tokenMaker, user.Username, util.BankerRole, sessionID)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.NoError(t, err)
+				require.Equal(t, util.BankerRole, payload.Role)
+			},
+		},
+		{
+			name:       "BankerFreezeAccount",
+			fullMethod: "/pb.SimpleBank/FreezeAccount",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithToken(t, server.tokenMaker, user.Username, util.BankerRole, sessionID)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:       "AdminBlockSession",
+			fullMethod: "/pb.SimpleBank/BlockSession",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			},
+			buildContext: func(t *testing.T, server *Server) context.Context {
+				return newContextWithToken(t, server.tokenMaker, user.Username, util.AdminRole, sessionID)
+			},
+			checkResponse: func(t *testing.T, payload *token.Payload, err error) {
+				require.NoError(t, err)
+				require.Equal(t, util.AdminRole, payload.Role)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			ctx := tc.buildContext(t, server)
+
+			var payload *token.Payload
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				var err error
+				payload, err = authPayloadFromContext(ctx)
+				return nil, err
+			}
+
+			info := &grpc.UnaryServerInfo{FullMethod: tc.fullMethod}
+			_, err := server.AuthInterceptor(ctx, nil, info, handler)
+			tc.checkResponse(t, payload, err)
+		})
+	}
+}
+
+func TestMethodPoliciesCoverAllMethods(t *testing.T) {
+	serviceMethods := make(map[string]bool)
+	for _, method := range pb.SimpleBank_ServiceDesc.Methods {
+		fullMethod := fmt.Sprintf("/%s/%s", pb.SimpleBank_ServiceDesc.ServiceName, method.MethodName)
+		serviceMethods[fullMethod] = true
+
+		_, ok := methodPolicies[fullMethod]
+		require.True(t, ok, "missing policy of %s", fullMethod)
+	}
+
+	// a policy of a renamed or removed method would silently fall back to the default
+	for fullMethod := range methodPolicies {
+		require.True(t, serviceMethods[fullMethod], "policy of unknown method %s", fullMethod)
+	}
+}
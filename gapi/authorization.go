@@ -2,11 +2,18 @@ package gapi
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
+	db "github.com/vladoohr/simple_bank/db/sqlc"
 	"github.com/vladoohr/simple_bank/token"
+	"github.com/vladoohr/simple_bank/util"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -14,19 +21,45 @@ const (
 	authorizationBearer = "bearer"
 )
 
+// authorizeUser verifies the access token of the call and returns its payload
+// Tokens issued before the last password change of the user are rejected,
+// which costs a GetUser query on every authenticated call
+// The returned errors are gRPC status errors
 func (server *Server) authorizeUser(ctx context.Context) (*token.Payload, error) {
 
 	m, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, fmt.Errorf("missing metadata")
+		return nil, unauthenticatedError(fmt.Errorf("missing metadata"))
 	}
 
 	values := m.Get(authorizationHeader)
 	if len(values) == 0 {
-		return nil, fmt.Errorf("missing authorization header")
+		return nil, unauthenticatedError(fmt.Errorf("missing authorization header"))
 	}
 
-	authHeader := values[0]
+	payload, err := server.verifyAuthorizationHeader(values[0])
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, unauthenticatedError(errors.New("user not found"))
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to get user: %s", err)
+	}
+
+	if err := payload.CheckPasswordChange(user.PasswordChangeAt); err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	return payload, nil
+}
+
+// verifyAuthorizationHeader verifies the bearer access token from the authorization header
+func (server *Server) verifyAuthorizationHeader(authHeader string) (*token.Payload, error) {
 	fields := strings.Fields(authHeader)
 	if len(fields) < 2 {
 		return nil, fmt.Errorf("invalid authorization header format")
@@ -43,5 +76,56 @@ func (server *Server) authorizeUser(ctx context.Context) (*token.Payload, error)
 		return nil, fmt.Errorf("invalid access token: %s", err)
 	}
 
+	if payload.IsChallenge() {
+		return nil, token.ErrChallengeToken
+	}
+
 	return payload, nil
 }
+
+// getOwnedAccount returns the account if it belongs to the given user
+func (server *Server) getOwnedAccount(ctx context.Context, accountID int64, username string) (db.Account, error) {
+	account, err := server.getAccount(ctx, accountID)
+	if err != nil {
+		return account, err
+	}
+
+	if account.Owner != username {
+		err := errors.New("account does not belong to the authenticated user")
+		return account, permissionDeniedError(accountResourceType, strconv.FormatInt(accountID, 10), err)
+	}
+
+	return account, nil
+}
+
+// getViewableAccount returns the account if it belongs to the authenticated user
+// or the user is allowed to view any account
+func (server *Server) getViewableAccount(ctx context.Context, accountID int64, authPayload *token.Payload) (db.Account, error) {
+	if util.CanViewAnyAccount(authPayload.Role) {
+		return server.getAccount(ctx, accountID)
+	}
+
+	return server.getOwnedAccount(ctx, accountID, authPayload.Username)
+}
+
+// revokeSessionFamily blocks the sessions rotated from the same login as the blocked session
+// and denies all of them, so older access tokens of the login are rejected as well
+func (server *Server) revokeSessionFamily(ctx context.Context, session db.Session) error {
+	family, err := server.store.BlockSessionFamily(ctx, session.FamilyID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to revoke session family: %s", err)
+	}
+
+	return server.denySessions(ctx, append(family, session)...)
+}
+
+// denySessions adds the blocked sessions to the denylist, so their access tokens are rejected right away
+func (server *Server) denySessions(ctx context.Context, sessions ...db.Session) error {
+	for _, session := range sessions {
+		if err := server.denylist.Add(ctx, session.ID, session.ExpiresAt); err != nil {
+			return status.Errorf(codes.Internal, "failed to revoke session: %s", err)
+		}
+	}
+
+	return nil
+}
@@ -11,7 +11,53 @@ func convertUser(user db.User) *pb.User {
 		Username:         user.Username,
 		FullName:         user.FullName,
 		Email:            user.Email,
+		Role:             user.Role,
+		IsEmailVerified:  user.IsEmailVerified,
+		IsTotpEnabled:    user.IsTotpEnabled,
 		CreatedAt:        timestamppb.New(user.CreatedAt),
 		PasswordChangeAt: timestamppb.New(user.PasswordChangeAt),
 	}
 }
+
+func convertAccount(account db.Account) *pb.Account {
+	return &pb.Account{
+		Id:        account.ID,
+		Owner:     account.Owner,
+		Balance:   account.Balance,
+		Currency:  account.Currency,
+		CreatedAt: timestamppb.New(account.CreatedAt),
+		IsFrozen:  account.IsFrozen,
+	}
+}
+
+func convertTransfer(transfer db.Transfer) *pb.Transfer {
+	return &pb.Transfer{
+		Id:            transfer.ID,
+		FromAccountId: transfer.FromAccountID,
+		ToAccountId:   transfer.ToAccountID,
+		Amount:        transfer.Amount,
+		CreatedAt:     timestamppb.New(transfer.CreatedAt),
+		ExchangeRate:  transfer.ExchangeRate,
+	}
+}
+
+func convertEntry(entry db.Entry) *pb.Entry {
+	return &pb.Entry{
+		Id:        entry.ID,
+		AccountId: entry.AccountID,
+		Amount:    entry.Amount,
+		CreatedAt: timestamppb.New(entry.CreatedAt),
+	}
+}
+
+func convertSession(session db.Session) *pb.Session {
+	return &pb.Session{
+		Id:        session.ID.String(),
+		Username:  session.Username,
+		UserAgent: session.UserAgent,
+		ClientIp:  session.ClientIp,
+		IsBlocked: session.IsBlocked,
+		ExpiresAt: timestamppb.New(session.ExpiresAt),
+		CreatedAt: timestamppb.New(session.CreatedAt),
+	}
+}
@@ -28,3 +28,47 @@ func invalidArgumentError(violations []*errdetails.BadRequest_FieldViolation) er
 func unauthenticatedError(err error) error {
 	return status.Errorf(codes.Unauthenticated, "unauthorized: %s", err)
 }
+
+func preconditionViolation(violationType string, subject string, err error) *errdetails.PreconditionFailure_Violation {
+	return &errdetails.PreconditionFailure_Violation{
+		Type:        violationType,
+		Subject:     subject,
+		Description: err.Error(),
+	}
+}
+
+func failedPreconditionError(violations []*errdetails.PreconditionFailure_Violation) error {
+	preconditionFailure := &errdetails.PreconditionFailure{Violations: violations}
+	statusFailed := status.New(codes.FailedPrecondition, "failed precondition")
+
+	statusDetails, err := statusFailed.WithDetails(preconditionFailure)
+	if err != nil {
+		return statusFailed.Err()
+	}
+
+	return statusDetails.Err()
+}
+
+func resourceError(code codes.Code, resourceType string, resourceName string, err error) error {
+	resourceInfo := &errdetails.ResourceInfo{
+		ResourceType: resourceType,
+		ResourceName: resourceName,
+		Description:  err.Error(),
+	}
+	statusResource := status.New(code, err.Error())
+
+	statusDetails, detailsErr := statusResource.WithDetails(resourceInfo)
+	if detailsErr != nil {
+		return statusResource.Err()
+	}
+
+	return statusDetails.Err()
+}
+
+func notFoundError(resourceType string, resourceName string, err error) error {
+	return resourceError(codes.NotFound, resourceType, resourceName, err)
+}
+
+func permissionDeniedError(resourceType string, resourceName string, err error) error {
+	return resourceError(codes.PermissionDenied, resourceType, resourceName, err)
+}
@@ -0,0 +1,148 @@
+package gapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// requestLog collects the log fields that are only known to the inner interceptors
+type requestLog struct {
+	username string
+}
+
+type requestLogContextKey struct{}
+
+// logUsernameHeader carries the authenticated user back to the gateway, which logs it and strips the header
+const logUsernameHeader = "x-log-username"
+
+// setLogUsername records the authenticated user in the log entry of the request
+// and in the response header, so the gateway log entry of the same request holds it as well
+func setLogUsername(ctx context.Context, username string) {
+	if entry, ok := ctx.Value(requestLogContextKey{}).(*requestLog); ok {
+		entry.username = username
+	}
+
+	// fails only outside of a gRPC call, when there is no response to set the header on
+	_ = grpc.SetHeader(ctx, metadata.Pairs(logUsernameHeader, username))
+}
+
+// GrpcLogger is a unary interceptor that logs every gRPC request
+// The username is filled in by the AuthInterceptor, so the access token is verified only once
+func (server *Server) GrpcLogger(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	entry := &requestLog{}
+
+	startTime := time.Now()
+	result, err := handler(context.WithValue(ctx, requestLogContextKey{}, entry), req)
+	duration := time.Since(startTime)
+
+	statusCode := codes.Unknown
+	if st, ok := status.FromError(err); ok {
+		statusCode = st.Code()
+	}
+
+	var peerAddress string
+	if p, ok := peer.FromContext(ctx); ok {
+		peerAddress = p.Addr.String()
+	}
+
+	logger := logEvent(runtime.HTTPStatusFromCode(statusCode))
+	if err != nil {
+		logger = logger.Err(err)
+	}
+
+	logger.Str("protocol", "grpc").
+		Str("method", info.FullMethod).
+		Int("status_code", int(statusCode)).
+		Str("status_text", statusCode.String()).
+		Dur("duration", duration).
+		Str("peer_address", peerAddress).
+		Str("username", entry.username).
+		Msg("received a gRPC request")
+
+	return result, err
+}
+
+// responseRecorder captures the status code and the authenticated user written by the wrapped handler
+type responseRecorder struct {
+	http.ResponseWriter
+	StatusCode    int
+	Username      string
+	headerWritten bool
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	rec.StatusCode = statusCode
+	rec.captureUsername()
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	rec.captureUsername()
+	return rec.ResponseWriter.Write(data)
+}
+
+// captureUsername takes the username forwarded by the gateway from the gRPC response header
+// and removes the header before it is sent to the client
+func (rec *responseRecorder) captureUsername() {
+	if rec.headerWritten {
+		return
+	}
+	rec.headerWritten = true
+
+	header := rec.Header()
+	key := runtime.MetadataHeaderPrefix + logUsernameHeader
+	rec.Username = header.Get(key)
+	header.Del(key)
+}
+
+// HTTPLogger is a middleware that logs every request served by the gateway
+// Only the path is logged, the query string can carry secrets like the verify email code
+func (server *Server) HTTPLogger(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		startTime := time.Now()
+		rec := &responseRecorder{
+			ResponseWriter: res,
+			StatusCode:     http.StatusOK,
+		}
+		handler.ServeHTTP(rec, req)
+		duration := time.Since(startTime)
+
+		logEvent(rec.StatusCode).Str("protocol", "http").
+			Str("method", req.Method).
+			Str("path", req.URL.Path).
+			Int("status_code", rec.StatusCode).
+			Str("status_text", http.StatusText(rec.StatusCode)).
+			Dur("duration", duration).
+			Str("peer_address", req.RemoteAddr).
+			Str("username", rec.Username).
+			Msg("received an HTTP request")
+	})
+}
+
+// logEvent returns the log event for the HTTP status of the response
+// gRPC codes are mapped to their HTTP status first, so both protocols log the same failures at the same level
+func logEvent(httpStatus int) *zerolog.Event {
+	switch {
+	case httpStatus >= http.StatusInternalServerError:
+		return log.Error()
+	case httpStatus >= http.StatusBadRequest:
+		return log.Warn()
+	default:
+		return log.Info()
+	}
+}
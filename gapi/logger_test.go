@@ -0,0 +1,189 @@
+package gapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"github.com/vladoohr/simple_bank/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// captureLog redirects the global logger into a buffer for the duration of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+
+	logger := log.Logger
+	log.Logger = zerolog.New(&buf)
+	t.Cleanup(func() {
+		log.Logger = logger
+	})
+
+	return &buf
+}
+
+func decodeLogEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	var entry map[string]interface{}
+	err := json.Unmarshal(buf.Bytes(), &entry)
+	require.NoError(t, err)
+
+	return entry
+}
+
+func TestGrpcLogger(t *testing.T) {
+	testCases := []struct {
+		name     string
+		handler  grpc.UnaryHandler
+		expected map[string]interface{}
+	}{
+		{
+			name: "OK",
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				setLogUsername(ctx, "alice")
+				return &pb.GetAccountResponse{}, nil
+			},
+			expected: map[string]interface{}{
+				"level":       "info",
+				"status_code": float64(codes.OK),
+				"status_text": "OK",
+				"username":    "alice",
+			},
+		},
+		{
+			name: "Unauthenticated",
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, status.Error(codes.Unauthenticated, "missing access token")
+			},
+			expected: map[string]interface{}{
+				"level":       "warn",
+				"status_code": float64(codes.Unauthenticated),
+				"status_text": "Unauthenticated",
+				"username":    "",
+				"error":       "rpc error: code = Unauthenticated desc = missing access token",
+			},
+		},
+		{
+			name: "InternalError",
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				setLogUsername(ctx, "alice")
+				return nil, status.Error(codes.Internal, "failed to get account")
+			},
+			expected: map[string]interface{}{
+				"level":       "error",
+				"status_code": float64(codes.Internal),
+				"status_text": "Internal",
+				"username":    "alice",
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			buf := captureLog(t)
+			server := &Server{}
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{
+				Addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 51234},
+			})
+			info := &grpc.UnaryServerInfo{FullMethod: "/pb.SimpleBank/GetAccount"}
+
+			_, _ = server.GrpcLogger(ctx, &pb.GetAccountRequest{}, info, tc.handler)
+
+			entry := decodeLogEntry(t, buf)
+			require.Equal(t, "grpc", entry["protocol"])
+			require.Equal(t, "/pb.SimpleBank/GetAccount", entry["method"])
+			require.Equal(t, "203.0.113.7:51234", entry["peer_address"])
+			require.Contains(t, entry, "duration")
+			for key, value := range tc.expected {
+				require.Equal(t, value, entry[key], key)
+			}
+		})
+	}
+}
+
+func TestHTTPLogger(t *testing.T) {
+	testCases := []struct {
+		name     string
+		handler  http.HandlerFunc
+		expected map[string]interface{}
+	}{
+		{
+			name: "OK",
+			handler: func(res http.ResponseWriter, req *http.Request) {
+				res.Header().Set("Grpc-Metadata-X-Log-Username", "alice")
+				res.Write([]byte("{}"))
+			},
+			expected: map[string]interface{}{
+				"level":       "info",
+				"status_code": float64(http.StatusOK),
+				"status_text": "OK",
+				"username":    "alice",
+			},
+		},
+		{
+			name: "NotFound",
+			handler: func(res http.ResponseWriter, req *http.Request) {
+				res.Header().Set("Grpc-Metadata-X-Log-Username", "alice")
+				res.WriteHeader(http.StatusNotFound)
+			},
+			expected: map[string]interface{}{
+				"level":       "warn",
+				"status_code": float64(http.StatusNotFound),
+				"status_text": "Not Found",
+				"username":    "alice",
+			},
+		},
+		{
+			name: "Unauthenticated",
+			handler: func(res http.ResponseWriter, req *http.Request) {
+				res.WriteHeader(http.StatusUnauthorized)
+			},
+			expected: map[string]interface{}{
+				"level":       "warn",
+				"status_code": float64(http.StatusUnauthorized),
+				"status_text": "Unauthorized",
+				"username":    "",
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			buf := captureLog(t)
+			server := &Server{}
+
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/v1/verify_email?email_id=1&secret_code=secret", nil)
+			request.RemoteAddr = "203.0.113.7:51234"
+
+			server.HTTPLogger(tc.handler).ServeHTTP(recorder, request)
+
+			// the username is only logged, it never reaches the client
+			require.Empty(t, recorder.Header().Get("Grpc-Metadata-X-Log-Username"))
+
+			entry := decodeLogEntry(t, buf)
+			require.Equal(t, "http", entry["protocol"])
+			require.Equal(t, http.MethodGet, entry["method"])
+			require.Equal(t, "/v1/verify_email", entry["path"])
+			require.Equal(t, "203.0.113.7:51234", entry["peer_address"])
+			require.Contains(t, entry, "duration")
+			for key, value := range tc.expected {
+				require.Equal(t, value, entry[key], key)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package gapi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/lockout"
+	"github.com/vladoohr/simple_bank/token"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func newTestServer(t *testing.T, store db.Store) *Server {
+	config := util.Config{
+		TokenType:               token.PasetoTokenType,
+		TokenSymmetricKey:       util.RandomString(32),
+		TokenDenylist:           token.MemoryDenylistType,
+		LoginLimiter:            lockout.MemoryLimiterType,
+		LoginMaxAttempts:        3,
+		LoginMaxAttemptsPerIP:   10,
+		LoginLockoutDuration:    time.Minute,
+		LoginMaxLockoutDuration: time.Hour,
+		TOTPChallengeDuration:   time.Minute,
+		AccessTokenDuration:     time.Minute,
+		RefreshTokenDuration:    time.Hour,
+	}
+
+	server, err := NewServer(config, store)
+	require.NoError(t, err)
+
+	return server
+}
+
+// newContextWithAuth returns a context holding the access token payload of the user,
+// the way the AuthInterceptor passes it to the handlers
+func newContextWithAuth(t *testing.T, tokenMaker token.Maker, username string, role string) context.Context {
+	_, payload, err := tokenMaker.CreateToken(username, role, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	return context.WithValue(context.Background(), authPayloadContextKey{}, payload)
+}
+
+func randomUser(t *testing.T) db.User {
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	return db.User{
+		Username:       util.RandomOwner(),
+		Role:           util.DepositorRole,
+		HashedPassword: hashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	}
+}
+
+func randomAccount(owner string) db.Account {
+	return db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    owner,
+		Currency: util.RandomCurrency(),
+		Balance:  util.RandomBalance(),
+	}
+}
@@ -3,25 +3,37 @@ package gapi
 import (
 	"context"
 	"fmt"
+	"net"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 )
 
 const (
-	xForwardedForHeader = "x-forwarded-for"
-	userAgentHeader     = "user-agent"
-	GrpcUserAgentHeader = "grpcgateway-user-agent"
+	xForwardedForHeader  = "x-forwarded-for"
+	userAgentHeader      = "user-agent"
+	GrpcUserAgentHeader  = "grpcgateway-user-agent"
+	IdempotencyKeyHeader = "idempotency-key"
 )
 
 type Metadata struct {
-	UserAgent string
-	ClientAPI string
+	UserAgent      string
+	ClientAPI      string
+	IdempotencyKey string
 }
 
-func extractMetadata(ctx context.Context) *Metadata {
+// extractMetadata reads the request metadata
+// The client IP is the peer address, unless the peer is a trusted proxy like the gateway,
+// then it is the right-most forwarded address, which the proxy appended itself
+// The other forwarded addresses are supplied by the client and cannot be trusted
+func (server *Server) extractMetadata(ctx context.Context) *Metadata {
 	mtdt := &Metadata{}
 
+	var forwardedFor []string
+
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		if userAgents := md.Get(userAgentHeader); len(userAgents) > 0 {
 			mtdt.UserAgent = userAgents[0]
@@ -31,15 +43,96 @@ func extractMetadata(ctx context.Context) *Metadata {
 			mtdt.UserAgent = userAgents[0]
 		}
 
-		if clientIPs := md.Get(xForwardedForHeader); len(clientIPs) > 0 {
-			mtdt.ClientAPI = clientIPs[0]
+		forwardedFor = md.Get(xForwardedForHeader)
+
+		if idempotencyKeys := md.Get(IdempotencyKeyHeader); len(idempotencyKeys) > 0 {
+			mtdt.IdempotencyKey = idempotencyKeys[0]
 		}
 	}
 
 	if p, ok := peer.FromContext(ctx); ok {
-		fmt.Printf("Metadata: %+v", p)
-		mtdt.ClientAPI = p.Addr.String()
+		mtdt.ClientAPI = hostIP(p.Addr.String())
+	}
+
+	if len(forwardedFor) > 0 && server.isTrustedProxy(mtdt.ClientAPI) {
+		addresses := strings.Split(forwardedFor[len(forwardedFor)-1], ",")
+		if clientIP := net.ParseIP(strings.TrimSpace(addresses[len(addresses)-1])); clientIP != nil {
+			mtdt.ClientAPI = clientIP.String()
+		}
 	}
 
 	return mtdt
 }
+
+// isTrustedProxy returns true if the address belongs to one of the configured trusted proxies
+func (server *Server) isTrustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range server.trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hostIP strips the port from the peer address
+func hostIP(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+
+	return host
+}
+
+// parseTrustedProxies parses the trusted proxy IPs and CIDR ranges
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	trustedProxies := make([]*net.IPNet, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy: %s", proxy)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			trustedProxies = append(trustedProxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+		}
+
+		trustedProxies = append(trustedProxies, ipNet)
+	}
+
+	return trustedProxies, nil
+}
+
+// HeaderMatcher forwards the idempotency key HTTP header to the gRPC metadata
+// and falls back to the default grpc-gateway matching for the other headers
+func HeaderMatcher(key string) (string, bool) {
+	if strings.ToLower(key) == IdempotencyKeyHeader {
+		return IdempotencyKeyHeader, true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
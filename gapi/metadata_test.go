@@ -0,0 +1,82 @@
+package gapi
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestExtractMetadataClientIP(t *testing.T) {
+	trustedProxies, err := parseTrustedProxies([]string{"127.0.0.1", "10.0.0.0/8"})
+	require.NoError(t, err)
+
+	server := &Server{trustedProxies: trustedProxies}
+
+	testCases := []struct {
+		name         string
+		peerAddress  string
+		forwardedFor []string
+		clientIP     string
+	}{
+		{
+			name:        "DirectCall",
+			peerAddress: "203.0.113.7:51234",
+			clientIP:    "203.0.113.7",
+		},
+		{
+			name:         "DirectCallSpoofedHeader",
+			peerAddress:  "203.0.113.7:51234",
+			forwardedFor: []string{"198.51.100.1"},
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "TrustedProxy",
+			peerAddress:  "127.0.0.1:40000",
+			forwardedFor: []string{"203.0.113.7"},
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "TrustedProxySpoofedHeader",
+			peerAddress:  "10.1.2.3:40000",
+			forwardedFor: []string{"198.51.100.1, 203.0.113.7"},
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "TrustedProxyInvalidHeader",
+			peerAddress:  "127.0.0.1:40000",
+			forwardedFor: []string{"not an ip"},
+			clientIP:     "127.0.0.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			peerAddress, err := net.ResolveTCPAddr("tcp", tc.peerAddress)
+			require.NoError(t, err)
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: peerAddress})
+			for _, value := range tc.forwardedFor {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(xForwardedForHeader, value))
+			}
+
+			mtdt := server.extractMetadata(ctx)
+			require.Equal(t, tc.clientIP, mtdt.ClientAPI)
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	trustedProxies, err := parseTrustedProxies([]string{"127.0.0.1", "::1", "10.0.0.0/8", ""})
+	require.NoError(t, err)
+	require.Len(t, trustedProxies, 3)
+
+	_, err = parseTrustedProxies([]string{"localhost"})
+	require.Error(t, err)
+
+	_, err = parseTrustedProxies([]string{"10.0.0.0/99"})
+	require.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const sessionResourceType = "session"
+
+// BlockSession validates the request and blocks a session together with the sessions rotated from the same login,
+// so none of their refresh tokens can renew access tokens anymore
+func (server *Server) BlockSession(ctx context.Context, req *pb.BlockSessionRequest) (*pb.BlockSessionResponse, error) {
+
+	if violations := validateBlockSessionRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	session, err := server.store.BlockSession(ctx, uuid.MustParse(req.GetSessionId()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err := fmt.Errorf("session [%s] not found", req.GetSessionId())
+			return nil, notFoundError(sessionResourceType, req.GetSessionId(), err)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to block session: %s", err)
+	}
+
+	if err := server.revokeSessionFamily(ctx, session); err != nil {
+		return nil, err
+	}
+
+	blockSessionResponse := &pb.BlockSessionResponse{
+		Session: convertSession(session),
+	}
+
+	return blockSessionResponse, nil
+}
+
+func validateBlockSessionRequest(req *pb.BlockSessionRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateSessionID(req.GetSessionId()); err != nil {
+		violations = append(violations, fieldViolation("session_id", err))
+	}
+
+	return violations
+}
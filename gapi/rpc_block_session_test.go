@@ -0,0 +1,123 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func randomSession(username string) db.Session {
+	return db.Session{
+		ID:           uuid.New(),
+		Username:     username,
+		FamilyID:     uuid.New(),
+		RefreshToken: util.RandomString(32),
+		UserAgent:    util.RandomString(10),
+		ClientIp:     "127.0.0.1",
+		ExpiresAt:    time.Now().Add(time.Hour).Truncate(time.Second),
+		CreatedAt:    time.Now().Truncate(time.Second),
+	}
+}
+
+func TestBlockSession(t *testing.T) {
+	user := randomUser(t)
+
+	// the refresh token of the old session was rotated into the blocked session
+	oldSession := randomSession(user.Username)
+	session := randomSession(user.Username)
+	session.FamilyID = oldSession.FamilyID
+
+	blockedSession := session
+	blockedSession.IsBlocked = true
+	blockedOldSession := oldSession
+	blockedOldSession.IsBlocked = true
+
+	testCases := []struct {
+		name          string
+		req           *pb.BlockSessionRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, server *Server, res *pb.BlockSessionResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.BlockSessionRequest{SessionId: session.ID.String()},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(blockedSession, nil)
+				store.EXPECT().BlockSessionFamily(gomock.Any(), gomock.Eq(session.FamilyID)).Times(1).Return([]db.Session{blockedOldSession}, nil)
+			},
+			checkResponse: func(t *testing.T, server *Server, res *pb.BlockSessionResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, session.ID.String(), res.GetSession().GetId())
+				require.True(t, res.GetSession().GetIsBlocked())
+
+				// the access tokens of the whole family are rejected right away
+				for _, id := range []uuid.UUID{session.ID, oldSession.ID} {
+					revoked, err := server.denylist.Contains(context.Background(), id)
+					require.NoError(t, err)
+					require.True(t, revoked)
+				}
+			},
+		},
+		{
+			name: "NotFound",
+			req:  &pb.BlockSessionRequest{SessionId: session.ID.String()},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(db.Session{}, sql.ErrNoRows)
+				store.EXPECT().BlockSessionFamily(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, res *pb.BlockSessionResponse, err error) {
+				require.Equal(t, codes.NotFound, status.Code(err))
+			},
+		},
+		{
+			name: "InvalidID",
+			req:  &pb.BlockSessionRequest{SessionId: "invalid"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, res *pb.BlockSessionResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name: "InternalError",
+			req:  &pb.BlockSessionRequest{SessionId: session.ID.String()},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(blockedSession, nil)
+				store.EXPECT().BlockSessionFamily(gomock.Any(), gomock.Eq(session.FamilyID)).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, server *Server, res *pb.BlockSessionResponse, err error) {
+				require.Equal(t, codes.Internal, status.Code(err))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			ctx := newContextWithAuth(t, server.tokenMaker, "admin", util.AdminRole)
+
+			res, err := server.BlockSession(ctx, tc.req)
+			tc.checkResponse(t, server, res, err)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/util"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recoveryCodeCount is the number of recovery codes generated when two-factor authentication is enabled
+const recoveryCodeCount = 10
+
+// ConfirmTOTP verifies a code generated with the secret from EnrollTOTP and enables two-factor authentication
+// Returns new recovery codes, which are stored hashed and cannot be shown again
+func (server *Server) ConfirmTOTP(ctx context.Context, req *pb.ConfirmTOTPRequest) (*pb.ConfirmTOTPResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if violations := validateConfirmTOTPRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get user: %s", err)
+	}
+
+	if user.IsTotpEnabled {
+		err := errors.New("two-factor authentication is already enabled")
+		violation := preconditionViolation(totpEnabledFailure, userResourceType, err)
+		return nil, failedPreconditionError([]*errdetails.PreconditionFailure_Violation{violation})
+	}
+
+	if user.TotpSecret == "" {
+		err := errors.New("TOTP secret has not been enrolled")
+		violation := preconditionViolation(totpNotEnrolledFailure, userResourceType, err)
+		return nil, failedPreconditionError([]*errdetails.PreconditionFailure_Violation{violation})
+	}
+
+	step, ok := util.CheckTOTPCode(user.TotpSecret, req.GetCode(), time.Now())
+	if !ok {
+		violation := fieldViolation("code", errors.New("does not match the enrolled secret"))
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{violation})
+	}
+
+	recoveryCodes, err := util.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%s", err)
+	}
+
+	hashedRecoveryCodes := make([]string, len(recoveryCodes))
+	for i, recoveryCode := range recoveryCodes {
+		hashedRecoveryCodes[i], err = util.HashPassword(recoveryCode)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to hash recovery code: %s", err)
+		}
+	}
+
+	result, err := server.store.EnableTOTPTx(ctx, db.EnableTOTPTxParams{
+		Username:            user.Username,
+		HashedRecoveryCodes: hashedRecoveryCodes,
+		TOTPStep:            step,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to enable two-factor authentication: %s", err)
+	}
+
+	confirmTOTPResponse := &pb.ConfirmTOTPResponse{
+		User:          convertUser(result.User),
+		RecoveryCodes: recoveryCodes,
+	}
+
+	return confirmTOTPResponse, nil
+}
+
+func validateConfirmTOTPRequest(req *pb.ConfirmTOTPRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateTOTPCode(req.GetCode()); err != nil {
+		violations = append(violations, fieldViolation("code", err))
+	}
+
+	return violations
+}
@@ -0,0 +1,60 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateAccount validates the request and creates new account for the authenticated user
+func (server *Server) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.CreateAccountResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if violations := validateCreateAccountRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	createAccountParams := db.CreateAccountParams{
+		Owner:    authPayload.Username,
+		Balance:  0,
+		Currency: req.GetCurrency(),
+	}
+
+	account, err := server.store.CreateAccount(ctx, createAccountParams)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				return nil, status.Errorf(codes.AlreadyExists, "account already exists: %s", err)
+			case "foreign_key_violation":
+				return nil, status.Errorf(codes.PermissionDenied, "cannot create account: %s", err)
+			}
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to create account: %s", err)
+	}
+
+	createAccountResponse := &pb.CreateAccountResponse{
+		Account: convertAccount(account),
+	}
+
+	return createAccountResponse, nil
+}
+
+func validateCreateAccountRequest(req *pb.CreateAccountRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateCurrency(req.GetCurrency()); err != nil {
+		violations = append(violations, fieldViolation("currency", err))
+	}
+
+	return violations
+}
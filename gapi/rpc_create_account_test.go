@@ -0,0 +1,138 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/token"
+	"github.com/vladoohr/simple_bank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateAccount(t *testing.T) {
+	user := randomUser(t)
+	account := randomAccount(user.Username)
+	account.Balance = 0
+
+	testCases := []struct {
+		name          string
+		req           *pb.CreateAccountRequest
+		buildStubs    func(store *mockdb.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.CreateAccountResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  &pb.CreateAccountRequest{Currency: account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreateAccountParams{
+					Owner:    user.Username,
+					Balance:  0,
+					Currency: account.Currency,
+				}
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Eq(arg)).Times(1).Return(account, nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAuth(t, tokenMaker, user.Username, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateAccountResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, account.ID, res.GetAccount().GetId())
+				require.Equal(t, user.Username, res.GetAccount().GetOwner())
+				require.Equal(t, account.Currency, res.GetAccount().GetCurrency())
+				require.Zero(t, res.GetAccount().GetBalance())
+			},
+		},
+		{
+			name: "Unauthenticated",
+			req:  &pb.CreateAccountRequest{Currency: account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateAccountResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name: "InvalidCurrency",
+			req:  &pb.CreateAccountRequest{Currency: "invalid"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAuth(t, tokenMaker, user.Username, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateAccountResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name: "DuplicateCurrency",
+			req:  &pb.CreateAccountRequest{Currency: account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, &pq.Error{Code: "23505"})
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAuth(t, tokenMaker, user.Username, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateAccountResponse, err error) {
+				require.Equal(t, codes.AlreadyExists, status.Code(err))
+			},
+		},
+		{
+			name: "UserNotFound",
+			req:  &pb.CreateAccountRequest{Currency: account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, &pq.Error{Code: "23503"})
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAuth(t, tokenMaker, user.Username, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateAccountResponse, err error) {
+				require.Equal(t, codes.PermissionDenied, status.Code(err))
+			},
+		},
+		{
+			name: "InternalError",
+			req:  &pb.CreateAccountRequest{Currency: account.Currency},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrConnDone)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAuth(t, tokenMaker, user.Username, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateAccountResponse, err error) {
+				require.Equal(t, codes.Internal, status.Code(err))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			ctx := tc.buildContext(t, server.tokenMaker)
+
+			res, err := server.CreateAccount(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
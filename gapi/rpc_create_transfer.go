@@ -0,0 +1,187 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/util"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	accountResourceType        = "account"
+	currencyMismatchFailure    = "CURRENCY_MISMATCH"
+	insufficientFundsFailure   = "INSUFFICIENT_FUNDS"
+	unsupportedExchangeFailure = "UNSUPPORTED_EXCHANGE"
+	accountFrozenFailure       = "ACCOUNT_FROZEN"
+)
+
+// CreateTransfer validates the request and transfers amount of money from one account to another
+func (server *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if violations := validateCreateTransferRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	fromAccount, err := server.validAccount(ctx, req.GetFromAccountId(), req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	if fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account does not belong to the authenticated user")
+		return nil, permissionDeniedError(accountResourceType, strconv.FormatInt(fromAccount.ID, 10), err)
+	}
+
+	toAccount, err := server.getAccount(ctx, req.GetToAccountId())
+	if err != nil {
+		return nil, err
+	}
+
+	mtdt := server.extractMetadata(ctx)
+	if err := val.ValidateIdempotencyKey(mtdt.IdempotencyKey); err != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation(IdempotencyKeyHeader, err)})
+	}
+
+	transferTxParams := db.TransferTxParams{
+		FromAccountId:  req.GetFromAccountId(),
+		ToAccountId:    req.GetToAccountId(),
+		Amount:         req.GetAmount(),
+		Username:       authPayload.Username,
+		IdempotencyKey: mtdt.IdempotencyKey,
+	}
+
+	var result db.TransferTxResult
+
+	if toAccount.Currency == fromAccount.Currency {
+		result, err = server.store.TransferTx(ctx, transferTxParams)
+	} else {
+		rate, rateErr := server.exchangeRate(req.GetAmount(), fromAccount.Currency, toAccount.Currency)
+		if rateErr != nil {
+			return nil, rateErr
+		}
+
+		result, err = server.store.ExchangeTransferTx(ctx, db.ExchangeTransferTxParams{
+			TransferTxParams: transferTxParams,
+			ExchangeRate:     rate,
+		})
+	}
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			violation := preconditionViolation(insufficientFundsFailure, fmt.Sprintf("%s/%d", accountResourceType, fromAccount.ID), err)
+			return nil, failedPreconditionError([]*errdetails.PreconditionFailure_Violation{violation})
+		}
+
+		if errors.Is(err, db.ErrAccountFrozen) {
+			// either of the accounts can be frozen
+			violation := preconditionViolation(accountFrozenFailure, accountResourceType, err)
+			return nil, failedPreconditionError([]*errdetails.PreconditionFailure_Violation{violation})
+		}
+
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) {
+			return nil, status.Errorf(codes.AlreadyExists, "%s", err)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to transfer money: %s", err)
+	}
+
+	createTransferResponse := &pb.CreateTransferResponse{
+		Transfer:    convertTransfer(result.Transfer),
+		FromAccount: convertAccount(result.FromAccount),
+		ToAccount:   convertAccount(result.ToAccount),
+		FromEntry:   convertEntry(result.FromEntry),
+		ToEntry:     convertEntry(result.ToEntry),
+	}
+
+	return createTransferResponse, nil
+}
+
+// validAccount checks that the account exists and its currency matches the given one
+func (server *Server) validAccount(ctx context.Context, accountID int64, currency string) (db.Account, error) {
+	account, err := server.getAccount(ctx, accountID)
+	if err != nil {
+		return account, err
+	}
+
+	return account, checkAccountCurrency(account, currency)
+}
+
+// getAccount returns the account or a not found error
+func (server *Server) getAccount(ctx context.Context, accountID int64) (db.Account, error) {
+	account, err := server.store.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err := fmt.Errorf("account [%d] not found", accountID)
+			return account, notFoundError(accountResourceType, strconv.FormatInt(accountID, 10), err)
+		}
+
+		return account, status.Errorf(codes.Internal, "failed to get account: %s", err)
+	}
+
+	return account, nil
+}
+
+// exchangeRate returns the rate to convert the amount between the currencies
+// and checks that the converted amount is not rounded down to zero
+func (server *Server) exchangeRate(amount int64, from string, to string) (float64, error) {
+	rate, err := server.exchangeRateProvider.GetExchangeRate(from, to)
+	if err != nil {
+		violation := preconditionViolation(unsupportedExchangeFailure, fmt.Sprintf("%s/%s", from, to), err)
+		return 0, failedPreconditionError([]*errdetails.PreconditionFailure_Violation{violation})
+	}
+
+	if util.ConvertAmount(amount, rate) <= 0 {
+		err := fmt.Errorf("amount %d %s is too small to be converted to %s", amount, from, to)
+		return 0, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation("amount", err)})
+	}
+
+	return rate, nil
+}
+
+// checkAccountCurrency checks that the account currency matches the given one
+func checkAccountCurrency(account db.Account, currency string) error {
+	if account.Currency != currency {
+		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+		violation := preconditionViolation(currencyMismatchFailure, fmt.Sprintf("%s/%d", accountResourceType, account.ID), err)
+		return failedPreconditionError([]*errdetails.PreconditionFailure_Violation{violation})
+	}
+
+	return nil
+}
+
+func validateCreateTransferRequest(req *pb.CreateTransferRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateAccountID(req.GetFromAccountId()); err != nil {
+		violations = append(violations, fieldViolation("from_account_id", err))
+	}
+
+	if err := val.ValidateAccountID(req.GetToAccountId()); err != nil {
+		violations = append(violations, fieldViolation("to_account_id", err))
+	}
+
+	if req.GetFromAccountId() == req.GetToAccountId() {
+		violations = append(violations, fieldViolation("to_account_id", errors.New("must differ from from_account_id")))
+	}
+
+	if err := val.ValidateAmount(req.GetAmount()); err != nil {
+		violations = append(violations, fieldViolation("amount", err))
+	}
+
+	if err := val.ValidateCurrency(req.GetCurrency()); err != nil {
+		violations = append(violations, fieldViolation("currency", err))
+	}
+
+	return violations
+}
@@ -8,12 +8,14 @@ import (
 	"github.com/vladoohr/simple_bank/pb"
 	"github.com/vladoohr/simple_bank/util"
 	"github.com/vladoohr/simple_bank/val"
+	"github.com/vladoohr/simple_bank/worker"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 // CreateUser validates the request and creates new user
+// A verification email is enqueued in the same transaction and sent by the task worker
 func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
 
 	if violations := validateCreateUserRequest(req); violations != nil {
@@ -25,14 +27,22 @@ func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 		return nil, status.Errorf(codes.Internal, "failed to hash password: %s", err)
 	}
 
-	createUserParams := db.CreateUserParams{
-		Username:       req.GetUsername(),
-		HashedPassword: hashedPassword,
-		FullName:       req.GetFullname(),
-		Email:          req.GetEmail(),
+	verifyEmailTask, err := worker.NewSendVerifyEmailTask(worker.PayloadSendVerifyEmail{Username: req.GetUsername()})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create verify email task: %s", err)
+	}
+
+	createUserTxParams := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.GetUsername(),
+			HashedPassword: hashedPassword,
+			FullName:       req.GetFullname(),
+			Email:          req.GetEmail(),
+		},
+		Tasks: []db.CreateTaskParams{verifyEmailTask},
 	}
 
-	user, err := server.store.CreateUser(ctx, createUserParams)
+	result, err := server.store.CreateUserTx(ctx, createUserTxParams)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			switch pqErr.Code.Name() {
@@ -45,7 +55,7 @@ func (server *Server) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 	}
 
 	createUserResponse := &pb.CreateUserResponse{
-		User: convertUser(user),
+		User: convertUser(result.User),
 	}
 
 	return createUserResponse, nil
@@ -0,0 +1,84 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Deposit validates the request and adds money to any account, the method is restricted to the bank staff
+// The optional idempotency key makes the deposit safe to retry
+func (server *Server) Deposit(ctx context.Context, req *pb.DepositRequest) (*pb.DepositResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if violations := validateBalanceChange(req.GetAccountId(), req.GetAmount(), req.GetCurrency()); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	mtdt := server.extractMetadata(ctx)
+	if err := val.ValidateIdempotencyKey(mtdt.IdempotencyKey); err != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation(IdempotencyKeyHeader, err)})
+	}
+
+	account, err := server.getAccount(ctx, req.GetAccountId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAccountCurrency(account, req.GetCurrency()); err != nil {
+		return nil, err
+	}
+
+	result, err := server.store.DepositTx(ctx, db.DepositTxParams{
+		AccountID:      account.ID,
+		Amount:         req.GetAmount(),
+		Username:       authPayload.Username,
+		IdempotencyKey: mtdt.IdempotencyKey,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrAccountFrozen) {
+			violation := preconditionViolation(accountFrozenFailure, fmt.Sprintf("%s/%d", accountResourceType, account.ID), err)
+			return nil, failedPreconditionError([]*errdetails.PreconditionFailure_Violation{violation})
+		}
+
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) {
+			return nil, status.Errorf(codes.AlreadyExists, "%s", err)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to deposit money: %s", err)
+	}
+
+	depositResponse := &pb.DepositResponse{
+		Account: convertAccount(result.Account),
+		Entry:   convertEntry(result.Entry),
+	}
+
+	return depositResponse, nil
+}
+
+func validateBalanceChange(accountID int64, amount int64, currency string) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateAccountID(accountID); err != nil {
+		violations = append(violations, fieldViolation("account_id", err))
+	}
+
+	if err := val.ValidateAmount(amount); err != nil {
+		violations = append(violations, fieldViolation("amount", err))
+	}
+
+	if err := val.ValidateCurrency(currency); err != nil {
+		violations = append(violations, fieldViolation("currency", err))
+	}
+
+	return violations
+}
@@ -0,0 +1,61 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	userResourceType       = "user"
+	totpEnabledFailure     = "TOTP_ENABLED"
+	totpNotEnrolledFailure = "TOTP_NOT_ENROLLED"
+)
+
+// EnrollTOTP generates new TOTP secret for the authenticated user
+// Two-factor authentication is enabled only after ConfirmTOTP verifies a code generated with the secret
+func (server *Server) EnrollTOTP(ctx context.Context, req *pb.EnrollTOTPRequest) (*pb.EnrollTOTPResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get user: %s", err)
+	}
+
+	if user.IsTotpEnabled {
+		err := errors.New("two-factor authentication is already enabled")
+		violation := preconditionViolation(totpEnabledFailure, userResourceType, err)
+		return nil, failedPreconditionError([]*errdetails.PreconditionFailure_Violation{violation})
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%s", err)
+	}
+
+	_, err = server.store.UpdateUser(ctx, db.UpdateUserParams{
+		Username:   user.Username,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update user: %s", err)
+	}
+
+	enrollTOTPResponse := &pb.EnrollTOTPResponse{
+		Secret:     secret,
+		OtpauthUri: util.TOTPURI(server.config.TOTPIssuer, user.Username, secret),
+	}
+
+	return enrollTOTPResponse, nil
+}
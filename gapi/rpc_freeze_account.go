@@ -0,0 +1,51 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FreezeAccount validates the request and freezes or unfreezes an account, so its balance can not be changed
+func (server *Server) FreezeAccount(ctx context.Context, req *pb.FreezeAccountRequest) (*pb.FreezeAccountResponse, error) {
+
+	if violations := validateFreezeAccountRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	account, err := server.store.UpdateAccountFrozen(ctx, db.UpdateAccountFrozenParams{
+		ID:       req.GetAccountId(),
+		IsFrozen: req.GetFrozen(),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err := fmt.Errorf("account [%d] not found", req.GetAccountId())
+			return nil, notFoundError(accountResourceType, strconv.FormatInt(req.GetAccountId(), 10), err)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to freeze account: %s", err)
+	}
+
+	freezeAccountResponse := &pb.FreezeAccountResponse{
+		Account: convertAccount(account),
+	}
+
+	return freezeAccountResponse, nil
+}
+
+func validateFreezeAccountRequest(req *pb.FreezeAccountRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateAccountID(req.GetAccountId()); err != nil {
+		violations = append(violations, fieldViolation("account_id", err))
+	}
+
+	return violations
+}
@@ -0,0 +1,41 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// GetAccount validates the request and returns an account viewable by the authenticated user
+func (server *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if violations := validateGetAccountRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	account, err := server.getViewableAccount(ctx, req.GetId(), authPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	getAccountResponse := &pb.GetAccountResponse{
+		Account: convertAccount(account),
+	}
+
+	return getAccountResponse, nil
+}
+
+func validateGetAccountRequest(req *pb.GetAccountRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateAccountID(req.GetId()); err != nil {
+		violations = append(violations, fieldViolation("id", err))
+	}
+
+	return violations
+}
@@ -0,0 +1,79 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetAccountStatement validates the request and returns the entries of an account viewable by the authenticated user
+// within the time range together with the opening, closing and running balances
+func (server *Server) GetAccountStatement(ctx context.Context, req *pb.GetAccountStatementRequest) (*pb.GetAccountStatementResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if violations := validateGetAccountStatementRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	account, err := server.getViewableAccount(ctx, req.GetAccountId(), authPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	statement, err := server.store.AccountStatementTx(ctx, db.AccountStatementTxParams{
+		AccountID: account.ID,
+		From:      req.GetFrom().AsTime(),
+		To:        req.GetTo().AsTime(),
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get account statement: %s", err)
+	}
+
+	getAccountStatementResponse := &pb.GetAccountStatementResponse{
+		Account:        convertAccount(account),
+		From:           req.GetFrom(),
+		To:             req.GetTo(),
+		OpeningBalance: statement.OpeningBalance,
+		ClosingBalance: statement.ClosingBalance,
+		Lines:          make([]*pb.StatementLine, 0, len(statement.Lines)),
+	}
+
+	for _, line := range statement.Lines {
+		getAccountStatementResponse.Lines = append(getAccountStatementResponse.Lines, &pb.StatementLine{
+			Entry:   convertEntry(line.Entry),
+			Balance: line.Balance,
+		})
+	}
+
+	return getAccountStatementResponse, nil
+}
+
+func validateGetAccountStatementRequest(req *pb.GetAccountStatementRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateAccountID(req.GetAccountId()); err != nil {
+		violations = append(violations, fieldViolation("account_id", err))
+	}
+
+	if req.GetFrom() == nil {
+		violations = append(violations, fieldViolation("from", errors.New("is required")))
+	}
+
+	if req.GetTo() == nil {
+		violations = append(violations, fieldViolation("to", errors.New("is required")))
+	}
+
+	if req.GetFrom() != nil && req.GetTo() != nil && !req.GetTo().AsTime().After(req.GetFrom().AsTime()) {
+		violations = append(violations, fieldViolation("to", errors.New("must be after from")))
+	}
+
+	return violations
+}
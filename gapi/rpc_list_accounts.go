@@ -0,0 +1,92 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/util"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListAccounts validates the request and returns a page of the authenticated user's accounts
+// Without page_id the accounts are listed with cursor based pagination
+func (server *Server) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if violations := validateListAccountsRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	var accounts []db.Account
+	listAccountsResponse := &pb.ListAccountsResponse{}
+
+	if req.GetPageId() > 0 {
+		listAccountsParams := db.ListAccountsParams{
+			Owner:  authPayload.Username,
+			Limit:  req.GetPageSize(),
+			Offset: (req.GetPageId() - 1) * req.GetPageSize(),
+		}
+
+		accounts, err = server.store.ListAccounts(ctx, listAccountsParams)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to list accounts: %s", err)
+		}
+	} else {
+		afterID, _ := util.DecodePageToken(req.GetPageToken())
+
+		// fetch one more account to know if there is a next page
+		listAccountsAfterParams := db.ListAccountsAfterParams{
+			Owner:     authPayload.Username,
+			AfterID:   afterID,
+			LimitSize: req.GetPageSize() + 1,
+		}
+
+		accounts, err = server.store.ListAccountsAfter(ctx, listAccountsAfterParams)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to list accounts: %s", err)
+		}
+
+		if len(accounts) > int(req.GetPageSize()) {
+			accounts = accounts[:req.GetPageSize()]
+			listAccountsResponse.NextPageToken = util.EncodePageToken(accounts[len(accounts)-1].ID)
+		}
+	}
+
+	listAccountsResponse.Accounts = make([]*pb.Account, 0, len(accounts))
+	for _, account := range accounts {
+		listAccountsResponse.Accounts = append(listAccountsResponse.Accounts, convertAccount(account))
+	}
+
+	return listAccountsResponse, nil
+}
+
+func validateListAccountsRequest(req *pb.ListAccountsRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetPageId() != 0 {
+		if err := val.ValidatePageID(req.GetPageId()); err != nil {
+			violations = append(violations, fieldViolation("page_id", err))
+		}
+
+		if len(req.GetPageToken()) > 0 {
+			violations = append(violations, fieldViolation("page_token", errors.New("cannot be used together with page_id")))
+		}
+	}
+
+	if err := val.ValidatePageSize(req.GetPageSize()); err != nil {
+		violations = append(violations, fieldViolation("page_size", err))
+	}
+
+	if _, err := util.DecodePageToken(req.GetPageToken()); err != nil {
+		violations = append(violations, fieldViolation("page_token", err))
+	}
+
+	return violations
+}
@@ -0,0 +1,171 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/token"
+	"github.com/vladoohr/simple_bank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestListAccounts(t *testing.T) {
+	user := randomUser(t)
+
+	n := 5
+	accounts := make([]db.Account, n+1)
+	for i := range accounts {
+		accounts[i] = randomAccount(user.Username)
+		accounts[i].ID = int64(i + 1)
+	}
+
+	testCases := []struct {
+		name          string
+		req           *pb.ListAccountsRequest
+		buildStubs    func(store *mockdb.MockStore)
+		buildContext  func(t *testing.T, tokenMaker token.Maker) context.Context
+		checkResponse func(t *testing.T, res *pb.ListAccountsResponse, err error)
+	}{
+		{
+			name: "OKPageID",
+			req:  &pb.ListAccountsRequest{PageId: 2, PageSize: int32(n)},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsParams{
+					Owner:  user.Username,
+					Limit:  int32(n),
+					Offset: int32(n),
+				}
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts[:n], nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAuth(t, tokenMaker, user.Username, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetAccounts(), n)
+				require.Empty(t, res.GetNextPageToken())
+			},
+		},
+		{
+			name: "OKPageToken",
+			req:  &pb.ListAccountsRequest{PageSize: int32(n)},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsAfterParams{
+					Owner:     user.Username,
+					AfterID:   0,
+					LimitSize: int32(n + 1),
+				}
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts, nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAuth(t, tokenMaker, user.Username, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetAccounts(), n)
+				require.Equal(t, util.EncodePageToken(accounts[n-1].ID), res.GetNextPageToken())
+
+				for _, account := range res.GetAccounts() {
+					require.Equal(t, user.Username, account.GetOwner())
+				}
+			},
+		},
+		{
+			name: "OKLastPage",
+			req:  &pb.ListAccountsRequest{PageSize: int32(n), PageToken: util.EncodePageToken(accounts[1].ID)},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAccountsAfterParams{
+					Owner:     user.Username,
+					AfterID:   accounts[1].ID,
+					LimitSize: int32(n + 1),
+				}
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Eq(arg)).Times(1).Return(accounts[2:], nil)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAuth(t, tokenMaker, user.Username, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.NoError(t, err)
+				require.Len(t, res.GetAccounts(), n-1)
+				require.Empty(t, res.GetNextPageToken())
+			},
+		},
+		{
+			name: "Unauthenticated",
+			req:  &pb.ListAccountsRequest{PageSize: int32(n)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return context.Background()
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name: "InvalidPageSize",
+			req:  &pb.ListAccountsRequest{PageSize: 1000},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAuth(t, tokenMaker, user.Username, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name: "PageIDWithPageToken",
+			req:  &pb.ListAccountsRequest{PageId: 1, PageSize: int32(n), PageToken: util.EncodePageToken(1)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAuth(t, tokenMaker, user.Username, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+		{
+			name: "InternalError",
+			req:  &pb.ListAccountsRequest{PageSize: int32(n)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAccountsAfter(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			buildContext: func(t *testing.T, tokenMaker token.Maker) context.Context {
+				return newContextWithAuth(t, tokenMaker, user.Username, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, res *pb.ListAccountsResponse, err error) {
+				require.Equal(t, codes.Internal, status.Code(err))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			ctx := tc.buildContext(t, server.tokenMaker)
+
+			res, err := server.ListAccounts(ctx, tc.req)
+			tc.checkResponse(t, res, err)
+		})
+	}
+}
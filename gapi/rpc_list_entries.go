@@ -0,0 +1,75 @@
+package gapi
+
+import (
+	"context"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/util"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListEntries validates the request and returns a page of entries of an account viewable by the authenticated user
+func (server *Server) ListEntries(ctx context.Context, req *pb.ListEntriesRequest) (*pb.ListEntriesResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if violations := validateListEntriesRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	_, err = server.getViewableAccount(ctx, req.GetAccountId(), authPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	afterID, _ := util.DecodePageToken(req.GetPageToken())
+
+	// fetch one more entry to know if there is a next page
+	listEntriesAfterParams := db.ListEntriesAfterParams{
+		AccountID: req.GetAccountId(),
+		AfterID:   afterID,
+		LimitSize: req.GetPageSize() + 1,
+	}
+
+	entries, err := server.store.ListEntriesAfter(ctx, listEntriesAfterParams)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list entries: %s", err)
+	}
+
+	listEntriesResponse := &pb.ListEntriesResponse{}
+
+	if len(entries) > int(req.GetPageSize()) {
+		entries = entries[:req.GetPageSize()]
+		listEntriesResponse.NextPageToken = util.EncodePageToken(entries[len(entries)-1].ID)
+	}
+
+	listEntriesResponse.Entries = make([]*pb.Entry, 0, len(entries))
+	for _, entry := range entries {
+		listEntriesResponse.Entries = append(listEntriesResponse.Entries, convertEntry(entry))
+	}
+
+	return listEntriesResponse, nil
+}
+
+func validateListEntriesRequest(req *pb.ListEntriesRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateAccountID(req.GetAccountId()); err != nil {
+		violations = append(violations, fieldViolation("account_id", err))
+	}
+
+	if err := val.ValidatePageSize(req.GetPageSize()); err != nil {
+		violations = append(violations, fieldViolation("page_size", err))
+	}
+
+	if _, err := util.DecodePageToken(req.GetPageToken()); err != nil {
+		violations = append(violations, fieldViolation("page_token", err))
+	}
+
+	return violations
+}
@@ -0,0 +1,30 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/vladoohr/simple_bank/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListSessions returns the active sessions of the authenticated user
+func (server *Server) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	sessions, err := server.store.ListActiveSessions(ctx, authPayload.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list sessions: %s", err)
+	}
+
+	listSessionsResponse := &pb.ListSessionsResponse{}
+	for _, session := range sessions {
+		listSessionsResponse.Sessions = append(listSessionsResponse.Sessions, convertSession(session))
+	}
+
+	return listSessionsResponse, nil
+}
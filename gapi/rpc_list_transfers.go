@@ -0,0 +1,77 @@
+package gapi
+
+import (
+	"context"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/util"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListTransfers validates the request and returns a page of incoming and outgoing transfers
+// of an account viewable by the authenticated user
+func (server *Server) ListTransfers(ctx context.Context, req *pb.ListTransfersRequest) (*pb.ListTransfersResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if violations := validateListTransfersRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	_, err = server.getViewableAccount(ctx, req.GetAccountId(), authPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	afterID, _ := util.DecodePageToken(req.GetPageToken())
+
+	// fetch one more transfer to know if there is a next page
+	listTransfersAfterParams := db.ListTransfersAfterParams{
+		FromAccountID: req.GetAccountId(),
+		ToAccountID:   req.GetAccountId(),
+		AfterID:       afterID,
+		LimitSize:     req.GetPageSize() + 1,
+	}
+
+	transfers, err := server.store.ListTransfersAfter(ctx, listTransfersAfterParams)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list transfers: %s", err)
+	}
+
+	listTransfersResponse := &pb.ListTransfersResponse{}
+
+	if len(transfers) > int(req.GetPageSize()) {
+		transfers = transfers[:req.GetPageSize()]
+		listTransfersResponse.NextPageToken = util.EncodePageToken(transfers[len(transfers)-1].ID)
+	}
+
+	listTransfersResponse.Transfers = make([]*pb.Transfer, 0, len(transfers))
+	for _, transfer := range transfers {
+		listTransfersResponse.Transfers = append(listTransfersResponse.Transfers, convertTransfer(transfer))
+	}
+
+	return listTransfersResponse, nil
+}
+
+func validateListTransfersRequest(req *pb.ListTransfersRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateAccountID(req.GetAccountId()); err != nil {
+		violations = append(violations, fieldViolation("account_id", err))
+	}
+
+	if err := val.ValidatePageSize(req.GetPageSize()); err != nil {
+		violations = append(violations, fieldViolation("page_size", err))
+	}
+
+	if _, err := util.DecodePageToken(req.GetPageToken()); err != nil {
+		violations = append(violations, fieldViolation("page_token", err))
+	}
+
+	return violations
+}
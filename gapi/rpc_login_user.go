@@ -4,55 +4,109 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
+	"github.com/google/uuid"
 	db "github.com/vladoohr/simple_bank/db/sqlc"
 	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/token"
 	"github.com/vladoohr/simple_bank/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // LoginUser validates the request, checks if user exists,
 // checks the password and generates the JWT or PASETO access token
 // Returns the generated access token and user information
+// Failed attempts are counted per username and client IP, which are locked after too many of them
+// Users with two-factor authentication get a challenge token instead, see LoginUserTOTP
 func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	mtdt := server.extractMetadata(ctx)
+
+	lockedUntil, err := server.loginLimiter.LockedUntil(ctx, req.GetUsername(), mtdt.ClientAPI)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check login lockout: %s", err)
+	}
+
+	if !lockedUntil.IsZero() {
+		return nil, loginLockedError(lockedUntil)
+	}
+
 	user, err := server.store.GetUser(ctx, req.GetUsername())
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, status.Errorf(codes.NotFound, "user not found")
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Errorf(codes.Internal, "failed to get user: %s", err)
 		}
-		return nil, status.Errorf(codes.Internal, "failed to get user: ", err)
 
+		util.SimulatePasswordCheck(req.GetPassword())
+		return nil, server.rejectLogin(ctx, req.GetUsername(), mtdt.ClientAPI)
 	}
 
 	err = util.CheckPassword(req.GetPassword(), user.HashedPassword)
 	if err != nil {
-		return nil, status.Errorf(codes.NotFound, "incorect password")
+		return nil, server.rejectLogin(ctx, req.GetUsername(), mtdt.ClientAPI)
+	}
+
+	if user.IsTotpEnabled {
+		return server.challengeLogin(user)
 	}
 
-	accessToken, accessTokenPayload, err := server.tokenMaker.CreateToken(req.GetUsername(), server.config.AccessTokenDuration)
+	return server.startLoginSession(ctx, user, mtdt)
+}
+
+// challengeLogin returns a short-lived challenge token, which is exchanged
+// for the session tokens together with a TOTP or recovery code in LoginUserTOTP
+// The failed attempts are not reset until the second factor is verified
+func (server *Server) challengeLogin(user db.User) (*pb.LoginUserResponse, error) {
+	challengeToken, challengePayload, err := server.tokenMaker.CreateToken(user.Username, token.ChallengeRole, uuid.Nil, server.config.TOTPChallengeDuration)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create access token: ", err)
+		return nil, status.Errorf(codes.Internal, "failed to create challenge token: %s", err)
+	}
+
+	loginUserResponse := &pb.LoginUserResponse{
+		TotpRequired:            true,
+		ChallengeToken:          challengeToken,
+		ChallengeTokenExpiredAt: timestamppb.New(challengePayload.ExpireAt),
 	}
 
-	refreshToken, refreshTokenPayload, err := server.tokenMaker.CreateToken(req.GetUsername(), server.config.RefreshTokenDuration)
+	return loginUserResponse, nil
+}
+
+// startLoginSession resets the failed login attempts, creates new session
+// and returns its access and refresh tokens
+func (server *Server) startLoginSession(ctx context.Context, user db.User, mtdt *Metadata) (*pb.LoginUserResponse, error) {
+	if err := server.loginLimiter.RecordSuccess(ctx, user.Username); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to reset login attempts: %s", err)
+	}
+
+	// both tokens are tied to the new session
+	sessionID := uuid.New()
+
+	accessToken, accessTokenPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, sessionID, server.config.AccessTokenDuration)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create access token: %s", err)
+	}
+
+	refreshToken, refreshTokenPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, sessionID, server.config.RefreshTokenDuration)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create refresh access token: ", err)
+		return nil, status.Errorf(codes.Internal, "failed to create refresh access token: %s", err)
 	}
 
-	mtdt := extractMetadata(ctx)
 	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
-		ID:           refreshTokenPayload.ID,
+		ID:           sessionID,
 		Username:     refreshTokenPayload.Username,
 		RefreshToken: refreshToken,
 		UserAgent:    mtdt.UserAgent,
 		ClientIp:     mtdt.ClientAPI,
 		IsBlocked:    false,
 		ExpiresAt:    refreshTokenPayload.ExpireAt,
+		FamilyID:     sessionID,
 	})
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create session: ", err)
+		return nil, status.Errorf(codes.Internal, "failed to create session: %s", err)
 	}
 
 	loginUserResponse := &pb.LoginUserResponse{
@@ -66,3 +120,27 @@ func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (
 
 	return loginUserResponse, nil
 }
+
+// rejectLogin records the failed login attempt and returns the same error
+// whether the username does not exist or the password is wrong
+func (server *Server) rejectLogin(ctx context.Context, username string, clientIP string) error {
+	if _, err := server.loginLimiter.RecordFailure(ctx, username, clientIP); err != nil {
+		return status.Errorf(codes.Internal, "failed to record login attempt: %s", err)
+	}
+
+	return status.Errorf(codes.Unauthenticated, "invalid credentials")
+}
+
+// loginLockedError tells the client when the login can be retried
+func loginLockedError(lockedUntil time.Time) error {
+	statusLocked := status.New(codes.ResourceExhausted, "too many failed login attempts, try again later")
+
+	statusDetails, err := statusLocked.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(time.Until(lockedUntil)),
+	})
+	if err != nil {
+		return statusLocked.Err()
+	}
+
+	return statusDetails.Err()
+}
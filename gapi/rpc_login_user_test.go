@@ -0,0 +1,69 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/lockout"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoginUserLockoutSpoofedClientIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	policy := lockout.Policy{
+		MaxUsernameAttempts: 10,
+		MaxClientIPAttempts: 2,
+		BaseDuration:        time.Minute,
+		MaxDuration:         time.Hour,
+	}
+	server := &Server{
+		store:        store,
+		loginLimiter: lockout.NewMemoryLimiter(store, policy),
+	}
+
+	peerAddress, err := net.ResolveTCPAddr("tcp", "203.0.113.7:51234")
+	require.NoError(t, err)
+
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(int(policy.MaxClientIPAttempts)).Return(db.User{}, sql.ErrNoRows)
+	store.EXPECT().
+		CreateAuditEvent(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateAuditEventParams) (db.AuditEvent, error) {
+			require.Equal(t, "203.0.113.7", arg.ClientIp)
+
+			return db.AuditEvent{ID: 1}, nil
+		})
+
+	// every call claims another client IP, but the peer is not a trusted proxy
+	for i := 0; i <= int(policy.MaxClientIPAttempts); i++ {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: peerAddress})
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(xForwardedForHeader, fmt.Sprintf("198.51.100.%d", i)))
+
+		_, err := server.LoginUser(ctx, &pb.LoginUserRequest{
+			Username: util.RandomOwner(),
+			Password: "wrong_password",
+		})
+
+		if i < int(policy.MaxClientIPAttempts) {
+			require.Equal(t, codes.Unauthenticated, status.Code(err))
+		} else {
+			require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LoginUserTOTP verifies the challenge token from LoginUser and the TOTP or recovery code of the user
+// Returns the session tokens like LoginUser, each challenge token can be exchanged for a session only once
+// Wrong codes count as failed login attempts, so the codes cannot be guessed while the challenge is valid
+func (server *Server) LoginUserTOTP(ctx context.Context, req *pb.LoginUserTOTPRequest) (*pb.LoginUserResponse, error) {
+
+	if violations := validateLoginUserTOTPRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	payload, err := server.tokenMaker.VerifyToken(req.GetChallengeToken())
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if !payload.IsChallenge() {
+		return nil, unauthenticatedError(errors.New("token is not a challenge token"))
+	}
+
+	mtdt := server.extractMetadata(ctx)
+
+	lockedUntil, err := server.loginLimiter.LockedUntil(ctx, payload.Username, mtdt.ClientAPI)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check login lockout: %s", err)
+	}
+
+	if !lockedUntil.IsZero() {
+		return nil, loginLockedError(lockedUntil)
+	}
+
+	user, err := server.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid credentials")
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to get user: %s", err)
+	}
+
+	if err := payload.CheckPasswordChange(user.PasswordChangeAt); err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if !user.IsTotpEnabled {
+		return nil, unauthenticatedError(errors.New("two-factor authentication is not enabled"))
+	}
+
+	_, err = server.store.UseLoginCodeTx(ctx, db.UseLoginCodeTxParams{
+		User: user,
+		Code: req.GetCode(),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, server.rejectLogin(ctx, user.Username, mtdt.ClientAPI)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to verify login code: %s", err)
+	}
+
+	_, err = server.store.UseChallengeTx(ctx, db.UseChallengeTxParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiredAt: payload.ExpireAt,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, unauthenticatedError(errors.New("challenge token has already been used"))
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to use challenge token: %s", err)
+	}
+
+	return server.startLoginSession(ctx, user, mtdt)
+}
+
+func validateLoginUserTOTPRequest(req *pb.LoginUserTOTPRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if req.GetChallengeToken() == "" {
+		violations = append(violations, fieldViolation("challenge_token", errors.New("is required")))
+	}
+
+	if err := val.ValidateLoginCode(req.GetCode()); err != nil {
+		violations = append(violations, fieldViolation("code", err))
+	}
+
+	return violations
+}
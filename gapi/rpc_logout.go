@@ -0,0 +1,41 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Logout blocks the session of the authenticated user, so its access and refresh tokens are rejected
+func (server *Server) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	session, err := server.store.BlockUserSession(ctx, db.BlockUserSessionParams{
+		ID:       authPayload.SessionID,
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err := fmt.Errorf("session [%s] not found", authPayload.SessionID)
+			return nil, notFoundError(sessionResourceType, authPayload.SessionID.String(), err)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to logout: %s", err)
+	}
+
+	if err := server.revokeSessionFamily(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return &pb.LogoutResponse{}, nil
+}
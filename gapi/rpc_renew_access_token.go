@@ -0,0 +1,124 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// RenewAccessToken validates the refresh token and its session and returns new access and refresh tokens
+// The refresh token is rotated, so each one can be used only once
+// Reusing a rotated refresh token blocks all sessions of its family
+// Refresh tokens issued before the last password change are rejected
+// The new tokens carry the current role of the user, so role changes apply on the next renewal
+func (server *Server) RenewAccessToken(ctx context.Context, req *pb.RenewAccessTokenRequest) (*pb.RenewAccessTokenResponse, error) {
+
+	if violations := validateRenewAccessTokenRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	tokenPayload, err := server.tokenMaker.VerifyToken(req.GetRefreshToken())
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	session, err := server.store.GetSession(ctx, tokenPayload.SessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err := fmt.Errorf("session [%s] not found", tokenPayload.SessionID)
+			return nil, notFoundError(sessionResourceType, tokenPayload.SessionID.String(), err)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to get session: %s", err)
+	}
+
+	if session.IsBlocked {
+		return nil, unauthenticatedError(errors.New("session is blocked"))
+	}
+
+	if session.Username != tokenPayload.Username {
+		return nil, unauthenticatedError(errors.New("incorrect session username"))
+	}
+
+	if session.RefreshToken != req.GetRefreshToken() {
+		return nil, unauthenticatedError(errors.New("mismatch refresh token"))
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		return nil, unauthenticatedError(errors.New("session has expired"))
+	}
+
+	user, err := server.store.GetUser(ctx, tokenPayload.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get user: %s", err)
+	}
+
+	if err := tokenPayload.CheckPasswordChange(user.PasswordChangeAt); err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	newSessionID := uuid.New()
+
+	refreshToken, refreshTokenPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, newSessionID, server.config.RefreshTokenDuration)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create refresh token: %s", err)
+	}
+
+	mtdt := server.extractMetadata(ctx)
+	result, err := server.store.RotateSessionTx(ctx, db.RotateSessionTxParams{
+		SessionID: session.ID,
+		NewSession: db.CreateSessionParams{
+			ID:           newSessionID,
+			Username:     tokenPayload.Username,
+			RefreshToken: refreshToken,
+			UserAgent:    mtdt.UserAgent,
+			ClientIp:     mtdt.ClientAPI,
+			IsBlocked:    false,
+			ExpiresAt:    refreshTokenPayload.ExpireAt,
+		},
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRefreshTokenReused) {
+			if err := server.denySessions(ctx, result.BlockedSessions...); err != nil {
+				return nil, err
+			}
+
+			return nil, unauthenticatedError(err)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to rotate session: %s", err)
+	}
+
+	accessToken, accessTokenPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, result.Session.ID, server.config.AccessTokenDuration)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create access token: %s", err)
+	}
+
+	renewAccessTokenResponse := &pb.RenewAccessTokenResponse{
+		SessionId:             result.Session.ID.String(),
+		AccessToken:           accessToken,
+		AccessTokenExpiredAt:  timestamppb.New(accessTokenPayload.ExpireAt),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiredAt: timestamppb.New(refreshTokenPayload.ExpireAt),
+	}
+
+	return renewAccessTokenResponse, nil
+}
+
+func validateRenewAccessTokenRequest(req *pb.RenewAccessTokenRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if len(req.GetRefreshToken()) == 0 {
+		violations = append(violations, fieldViolation("refresh_token", errors.New("must not be empty")))
+	}
+
+	return violations
+}
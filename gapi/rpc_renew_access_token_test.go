@@ -0,0 +1,207 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRenewAccessToken(t *testing.T) {
+	user := randomUser(t)
+	session := randomSession(user.Username)
+	session.FamilyID = session.ID
+
+	// the refresh token of the session was already rotated into the newer session
+	rotatedSession := randomSession(user.Username)
+	rotatedSession.FamilyID = session.FamilyID
+
+	testCases := []struct {
+		name          string
+		setupSession  func(session *db.Session)
+		buildStubs    func(store *mockdb.MockStore, session db.Session)
+		checkResponse func(t *testing.T, server *Server, res *pb.RenewAccessTokenResponse, err error)
+	}{
+		{
+			name:         "OK",
+			setupSession: func(session *db.Session) {},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RotateSessionTxParams) (db.RotateSessionTxResult, error) {
+						require.Equal(t, session.ID, arg.SessionID)
+						require.Equal(t, user.Username, arg.NewSession.Username)
+						require.NotEqual(t, session.ID, arg.NewSession.ID)
+						require.NotEqual(t, session.RefreshToken, arg.NewSession.RefreshToken)
+
+						newSession := db.Session{
+							ID:           arg.NewSession.ID,
+							Username:     arg.NewSession.Username,
+							RefreshToken: arg.NewSession.RefreshToken,
+							ExpiresAt:    arg.NewSession.ExpiresAt,
+							FamilyID:     session.FamilyID,
+						}
+						return db.RotateSessionTxResult{Session: newSession}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, res *pb.RenewAccessTokenResponse, err error) {
+				require.NoError(t, err)
+				require.NotEqual(t, session.ID.String(), res.GetSessionId())
+				require.NotEmpty(t, res.GetRefreshToken())
+
+				accessPayload, err := server.tokenMaker.VerifyToken(res.GetAccessToken())
+				require.NoError(t, err)
+				require.Equal(t, res.GetSessionId(), accessPayload.SessionID.String())
+				require.Equal(t, user.Role, accessPayload.Role)
+
+				refreshPayload, err := server.tokenMaker.VerifyToken(res.GetRefreshToken())
+				require.NoError(t, err)
+				require.Equal(t, res.GetSessionId(), refreshPayload.SessionID.String())
+			},
+		},
+		{
+			name:         "RefreshTokenReused",
+			setupSession: func(session *db.Session) {},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				blockedSession := session
+				blockedSession.IsBlocked = true
+				blockedRotatedSession := rotatedSession
+				blockedRotatedSession.IsBlocked = true
+
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RotateSessionTxResult{BlockedSessions: []db.Session{blockedSession, blockedRotatedSession}}, db.ErrRefreshTokenReused)
+			},
+			checkResponse: func(t *testing.T, server *Server, res *pb.RenewAccessTokenResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+				require.Nil(t, res)
+
+				// the access tokens of the whole family are rejected right away
+				for _, id := range []uuid.UUID{session.ID, rotatedSession.ID} {
+					revoked, err := server.denylist.Contains(context.Background(), id)
+					require.NoError(t, err)
+					require.True(t, revoked)
+				}
+			},
+		},
+		{
+			name: "BlockedSession",
+			setupSession: func(session *db.Session) {
+				session.IsBlocked = true
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().RotateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, res *pb.RenewAccessTokenResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name: "MismatchRefreshToken",
+			setupSession: func(session *db.Session) {
+				session.RefreshToken = util.RandomString(32)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().RotateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, res *pb.RenewAccessTokenResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name: "ExpiredSession",
+			setupSession: func(session *db.Session) {
+				session.ExpiresAt = time.Now().Add(-time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().RotateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, res *pb.RenewAccessTokenResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name:         "PasswordChanged",
+			setupSession: func(session *db.Session) {},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				changedUser := user
+				changedUser.PasswordChangeAt = time.Now().Add(time.Second)
+
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(changedUser, nil)
+				store.EXPECT().RotateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, res *pb.RenewAccessTokenResponse, err error) {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+			},
+		},
+		{
+			name:         "SessionNotFound",
+			setupSession: func(session *db.Session) {},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(db.Session{}, sql.ErrNoRows)
+				store.EXPECT().RotateSessionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, res *pb.RenewAccessTokenResponse, err error) {
+				require.Equal(t, codes.NotFound, status.Code(err))
+			},
+		},
+		{
+			name:         "InternalError",
+			setupSession: func(session *db.Session) {},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					RotateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.RotateSessionTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, server *Server, res *pb.RenewAccessTokenResponse, err error) {
+				require.Equal(t, codes.Internal, status.Code(err))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			refreshToken, _, err := server.tokenMaker.CreateToken(user.Username, user.Role, session.ID, time.Minute)
+			require.NoError(t, err)
+
+			tokenSession := session
+			tokenSession.RefreshToken = refreshToken
+			tc.setupSession(&tokenSession)
+			tc.buildStubs(store, tokenSession)
+
+			res, err := server.RenewAccessToken(context.Background(), &pb.RenewAccessTokenRequest{RefreshToken: refreshToken})
+			tc.checkResponse(t, server, res, err)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/val"
+	"github.com/vladoohr/simple_bank/worker"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RequestPasswordReset enqueues an email with a password reset link for the user with the given email
+// The response is the same whether the email is registered or not, so it can not be used to find users
+func (server *Server) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*pb.RequestPasswordResetResponse, error) {
+
+	if violations := validateRequestPasswordResetRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, req.GetEmail())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &pb.RequestPasswordResetResponse{}, nil
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to get user: %s", err)
+	}
+
+	passwordResetTask, err := worker.NewSendPasswordResetTask(worker.PayloadSendPasswordReset{Username: user.Username})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create password reset task: %s", err)
+	}
+
+	if _, err := server.store.CreateTask(ctx, passwordResetTask); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to enqueue password reset task: %s", err)
+	}
+
+	return &pb.RequestPasswordResetResponse{}, nil
+}
+
+func validateRequestPasswordResetRequest(req *pb.RequestPasswordResetRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateEmail(req.GetEmail()); err != nil {
+		violations = append(violations, fieldViolation("email", err))
+	}
+
+	return violations
+}
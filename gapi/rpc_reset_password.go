@@ -0,0 +1,62 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/util"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const passwordResetResourceType = "password_reset"
+
+// ResetPassword uses the single-use reset token to set a new password
+// All sessions of the user are blocked, so the tokens issued with the old password stop working
+func (server *Server) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.ResetPasswordResponse, error) {
+
+	if violations := validateResetPasswordRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	hashedPassword, err := util.HashPassword(req.GetNewPassword())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to hash password: %s", err)
+	}
+
+	result, err := server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash:      util.HashToken(req.GetToken()),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err := errors.New("reset token is invalid, expired or already used")
+			return nil, notFoundError(passwordResetResourceType, "token", err)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to reset password: %s", err)
+	}
+
+	if err := server.denySessions(ctx, result.BlockedSessions...); err != nil {
+		return nil, err
+	}
+
+	return &pb.ResetPasswordResponse{}, nil
+}
+
+func validateResetPasswordRequest(req *pb.ResetPasswordRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateResetToken(req.GetToken()); err != nil {
+		violations = append(violations, fieldViolation("token", err))
+	}
+
+	if err := val.ValidatePassword(req.GetNewPassword()); err != nil {
+		violations = append(violations, fieldViolation("new_password", err))
+	}
+
+	return violations
+}
@@ -0,0 +1,33 @@
+package gapi
+
+import (
+	"context"
+
+	"github.com/vladoohr/simple_bank/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RevokeAllSessions blocks all sessions of the authenticated user
+func (server *Server) RevokeAllSessions(ctx context.Context, req *pb.RevokeAllSessionsRequest) (*pb.RevokeAllSessionsResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	sessions, err := server.store.BlockUserSessions(ctx, authPayload.Username)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to revoke sessions: %s", err)
+	}
+
+	if err := server.denySessions(ctx, sessions...); err != nil {
+		return nil, err
+	}
+
+	revokeAllSessionsResponse := &pb.RevokeAllSessionsResponse{
+		RevokedCount: int64(len(sessions)),
+	}
+
+	return revokeAllSessionsResponse, nil
+}
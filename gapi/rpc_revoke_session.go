@@ -0,0 +1,61 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RevokeSession validates the request and blocks a session of the authenticated user
+func (server *Server) RevokeSession(ctx context.Context, req *pb.RevokeSessionRequest) (*pb.RevokeSessionResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if violations := validateRevokeSessionRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	// sessions of other users are reported as not found
+	session, err := server.store.BlockUserSession(ctx, db.BlockUserSessionParams{
+		ID:       uuid.MustParse(req.GetSessionId()),
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err := fmt.Errorf("session [%s] not found", req.GetSessionId())
+			return nil, notFoundError(sessionResourceType, req.GetSessionId(), err)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to revoke session: %s", err)
+	}
+
+	if err := server.revokeSessionFamily(ctx, session); err != nil {
+		return nil, err
+	}
+
+	revokeSessionResponse := &pb.RevokeSessionResponse{
+		Session: convertSession(session),
+	}
+
+	return revokeSessionResponse, nil
+}
+
+func validateRevokeSessionRequest(req *pb.RevokeSessionRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateSessionID(req.GetSessionId()); err != nil {
+		violations = append(violations, fieldViolation("session_id", err))
+	}
+
+	return violations
+}
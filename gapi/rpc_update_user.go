@@ -10,6 +10,7 @@ import (
 	"github.com/vladoohr/simple_bank/pb"
 	"github.com/vladoohr/simple_bank/util"
 	"github.com/vladoohr/simple_bank/val"
+	"github.com/vladoohr/simple_bank/worker"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -18,7 +19,7 @@ import (
 // UpdateUser validates the request and updates new user
 func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UpdateUserResponse, error) {
 
-	authPayload, err := server.authorizeUser(ctx)
+	authPayload, err := authPayloadFromContext(ctx)
 	if err != nil {
 		return nil, unauthenticatedError(err)
 	}
@@ -67,7 +68,21 @@ func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 
 	}
 
-	user, err := server.store.UpdateUser(ctx, updateUserParams)
+	updateUserTxParams := db.UpdateUserTxParams{
+		UpdateUserParams: updateUserParams,
+	}
+
+	// a new email address is verified again, the task is only enqueued if the email really changes
+	if req.GetEmail() != nil {
+		verifyEmailTask, err := worker.NewSendVerifyEmailTask(worker.PayloadSendVerifyEmail{Username: req.GetUsername()})
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to create verify email task: %s", err)
+		}
+
+		updateUserTxParams.EmailChangeTasks = []db.CreateTaskParams{verifyEmailTask}
+	}
+
+	result, err := server.store.UpdateUserTx(ctx, updateUserTxParams)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, status.Errorf(codes.NotFound, "user not found")
@@ -77,7 +92,7 @@ func (server *Server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest)
 	}
 
 	UpdateUserResponse := &pb.UpdateUserResponse{
-		User: convertUser(user),
+		User: convertUser(result.User),
 	}
 
 	return UpdateUserResponse, nil
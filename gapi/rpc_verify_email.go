@@ -0,0 +1,56 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const verifyEmailResourceType = "verify_email"
+
+// VerifyEmail checks the secret code sent to the user and marks the email as verified
+func (server *Server) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.VerifyEmailResponse, error) {
+
+	if violations := validateVerifyEmailRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	result, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:    req.GetEmailId(),
+		SecretCode: req.GetSecretCode(),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err := errors.New("verification code is invalid, expired, already used or sent to a previous email")
+			return nil, notFoundError(verifyEmailResourceType, strconv.FormatInt(req.GetEmailId(), 10), err)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to verify email: %s", err)
+	}
+
+	verifyEmailResponse := &pb.VerifyEmailResponse{
+		IsVerified: result.User.IsEmailVerified,
+	}
+
+	return verifyEmailResponse, nil
+}
+
+func validateVerifyEmailRequest(req *pb.VerifyEmailRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := val.ValidateEmailID(req.GetEmailId()); err != nil {
+		violations = append(violations, fieldViolation("email_id", err))
+	}
+
+	if err := val.ValidateSecretCode(req.GetSecretCode()); err != nil {
+		violations = append(violations, fieldViolation("secret_code", err))
+	}
+
+	return violations
+}
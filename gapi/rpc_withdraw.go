@@ -0,0 +1,73 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/val"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Withdraw validates the request and takes money out of an account owned by the authenticated user
+// The optional idempotency key makes the withdrawal safe to retry
+func (server *Server) Withdraw(ctx context.Context, req *pb.WithdrawRequest) (*pb.WithdrawResponse, error) {
+
+	authPayload, err := authPayloadFromContext(ctx)
+	if err != nil {
+		return nil, unauthenticatedError(err)
+	}
+
+	if violations := validateBalanceChange(req.GetAccountId(), req.GetAmount(), req.GetCurrency()); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	mtdt := server.extractMetadata(ctx)
+	if err := val.ValidateIdempotencyKey(mtdt.IdempotencyKey); err != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation(IdempotencyKeyHeader, err)})
+	}
+
+	account, err := server.getOwnedAccount(ctx, req.GetAccountId(), authPayload.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAccountCurrency(account, req.GetCurrency()); err != nil {
+		return nil, err
+	}
+
+	result, err := server.store.WithdrawTx(ctx, db.WithdrawTxParams{
+		AccountID:      account.ID,
+		Amount:         req.GetAmount(),
+		Username:       authPayload.Username,
+		IdempotencyKey: mtdt.IdempotencyKey,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			violation := preconditionViolation(insufficientFundsFailure, fmt.Sprintf("%s/%d", accountResourceType, account.ID), err)
+			return nil, failedPreconditionError([]*errdetails.PreconditionFailure_Violation{violation})
+		}
+
+		if errors.Is(err, db.ErrAccountFrozen) {
+			violation := preconditionViolation(accountFrozenFailure, fmt.Sprintf("%s/%d", accountResourceType, account.ID), err)
+			return nil, failedPreconditionError([]*errdetails.PreconditionFailure_Violation{violation})
+		}
+
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) {
+			return nil, status.Errorf(codes.AlreadyExists, "%s", err)
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to withdraw money: %s", err)
+	}
+
+	withdrawResponse := &pb.WithdrawResponse{
+		Account: convertAccount(result.Account),
+		Entry:   convertEntry(result.Entry),
+	}
+
+	return withdrawResponse, nil
+}
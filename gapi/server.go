@@ -2,8 +2,10 @@ package gapi
 
 import (
 	"fmt"
+	"net"
 
 	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/lockout"
 	"github.com/vladoohr/simple_bank/pb"
 	"github.com/vladoohr/simple_bank/token"
 	"github.com/vladoohr/simple_bank/util"
@@ -11,24 +13,52 @@ import (
 
 // Server server grpc requests
 type Server struct {
-	config     util.Config
-	store      db.Store
-	tokenMaker token.Maker
+	config               util.Config
+	store                db.Store
+	tokenMaker           token.Maker
+	denylist             token.Denylist
+	loginLimiter         lockout.Limiter
+	trustedProxies       []*net.IPNet
+	exchangeRateProvider util.ExchangeRateProvider
 	pb.UnimplementedSimpleBankServer
 }
 
 // NewServer creates new grpc sesrver and setup routing
 func NewServer(config util.Config, store db.Store) (*Server, error) {
 
-	tokenMaker, err := token.NewPasetoMaker([]byte(config.TokenSymmetricKey))
+	tokenMaker, err := token.NewMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create token maker: %w", err)
 	}
 
+	denylist, err := token.NewDenylist(config.TokenDenylist, store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token denylist: %w", err)
+	}
+
+	loginLimiter, err := lockout.NewLimiter(config.LoginLimiter, store, lockout.NewPolicy(config))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create login limiter: %w", err)
+	}
+
+	trustedProxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	exchangeRateProvider, err := util.NewExchangeRateProvider(config.ExchangeRatesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exchange rate provider: %w", err)
+	}
+
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:               config,
+		store:                store,
+		tokenMaker:           tokenMaker,
+		denylist:             denylist,
+		loginLimiter:         loginLimiter,
+		trustedProxies:       trustedProxies,
+		exchangeRateProvider: exchangeRateProvider,
 	}
 
 	return server, nil
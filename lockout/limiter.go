@@ -0,0 +1,370 @@
+package lockout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+// Constants for the supported limiter types
+const (
+	MemoryLimiterType   = "memory"
+	PostgresLimiterType = "postgres"
+)
+
+// Constants for the kinds of keys the failed attempts are counted by
+const (
+	UsernameKind = "username"
+	ClientIPKind = "client_ip"
+)
+
+// LockoutEventType is the audit event type recorded when a key gets locked
+const LockoutEventType = "login_lockout"
+
+// Policy configures when and for how long the keys are locked
+type Policy struct {
+	// MaxUsernameAttempts is the number of failed attempts per username before it is locked
+	MaxUsernameAttempts int32
+	// MaxClientIPAttempts is the number of failed attempts per client IP before it is locked
+	MaxClientIPAttempts int32
+	// BaseDuration is the first lockout, every further failed attempt doubles it
+	BaseDuration time.Duration
+	// MaxDuration caps the lockout and is also the time after which the failed attempts are forgotten
+	MaxDuration time.Duration
+}
+
+// NewPolicy returns the lockout policy from the configuration
+func NewPolicy(config util.Config) Policy {
+	return Policy{
+		MaxUsernameAttempts: config.LoginMaxAttempts,
+		MaxClientIPAttempts: config.LoginMaxAttemptsPerIP,
+		BaseDuration:        config.LoginLockoutDuration,
+		MaxDuration:         config.LoginMaxLockoutDuration,
+	}
+}
+
+// lockoutDuration returns how long the key is locked after the given number of failed attempts
+func (policy Policy) lockoutDuration(kind string, failures int32) time.Duration {
+	maxAttempts := policy.MaxUsernameAttempts
+	if kind == ClientIPKind {
+		maxAttempts = policy.MaxClientIPAttempts
+	}
+
+	if failures < maxAttempts {
+		return 0
+	}
+
+	duration := policy.BaseDuration
+	for i := maxAttempts; i < failures && duration < policy.MaxDuration; i++ {
+		duration *= 2
+	}
+
+	if duration > policy.MaxDuration {
+		duration = policy.MaxDuration
+	}
+
+	return duration
+}
+
+// Lockout describes a key locked by a failed attempt
+type Lockout struct {
+	Kind        string    `json:"kind"`
+	Key         string    `json:"key"`
+	Failures    int32     `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// Limiter tracks the failed login attempts per username and per client IP
+type Limiter interface {
+	// LockedUntil returns when the lockout of the username or the client IP ends, zero time if neither is locked
+	LockedUntil(ctx context.Context, username string, clientIP string) (time.Time, error)
+
+	// RecordFailure counts the failed attempt, locks the keys that reached the limit and audits the lockouts
+	RecordFailure(ctx context.Context, username string, clientIP string) ([]Lockout, error)
+
+	// RecordSuccess forgets the failed attempts of the username
+	RecordSuccess(ctx context.Context, username string) error
+}
+
+// NewLimiter returns new limiter of the given type
+// The lockout events are audited through the store for every type
+func NewLimiter(limiterType string, store db.Querier, policy Policy) (Limiter, error) {
+	if policy.MaxUsernameAttempts < 1 || policy.MaxClientIPAttempts < 1 {
+		return nil, fmt.Errorf("max login attempts must be positive")
+	}
+
+	if policy.BaseDuration <= 0 || policy.MaxDuration < policy.BaseDuration {
+		return nil, fmt.Errorf("invalid lockout durations, must be 0 < base <= max")
+	}
+
+	switch limiterType {
+	case MemoryLimiterType:
+		return NewMemoryLimiter(store, policy), nil
+	case PostgresLimiterType:
+		return NewPostgresLimiter(store, policy), nil
+	default:
+		return nil, fmt.Errorf("unsupported login limiter type: %s", limiterType)
+	}
+}
+
+// auditLockouts logs the lockouts and stores them as audit events
+func auditLockouts(ctx context.Context, store db.Querier, username string, clientIP string, lockouts []Lockout) error {
+	for _, lockout := range lockouts {
+		log.Warn().
+			Str("kind", lockout.Kind).
+			Str("key", lockout.Key).
+			Int32("failures", lockout.Failures).
+			Time("locked_until", lockout.LockedUntil).
+			Msg("login locked")
+
+		details, err := json.Marshal(lockout)
+		if err != nil {
+			return fmt.Errorf("failed to marshal lockout: %w", err)
+		}
+
+		_, err = store.CreateAuditEvent(ctx, db.CreateAuditEventParams{
+			EventType: LockoutEventType,
+			Username:  username,
+			ClientIp:  clientIP,
+			Details:   details,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to audit lockout: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// attempts holds the failed attempts of a single key
+type attempts struct {
+	failures     int32
+	lastFailedAt time.Time
+	lockedUntil  time.Time
+}
+
+// maxMemoryKeys caps the keys kept by the in-memory limiter,
+// so failures with ever new usernames or client IPs can not grow it without bound
+const maxMemoryKeys = 100000
+
+// MemoryLimiter keeps the failed attempts in memory of a single server instance
+type MemoryLimiter struct {
+	mutex    sync.Mutex
+	store    db.Querier
+	policy   Policy
+	maxKeys  int
+	attempts map[string]*attempts
+}
+
+// NewMemoryLimiter returns new empty in-memory limiter
+func NewMemoryLimiter(store db.Querier, policy Policy) Limiter {
+	return &MemoryLimiter{
+		store:    store,
+		policy:   policy,
+		maxKeys:  maxMemoryKeys,
+		attempts: make(map[string]*attempts),
+	}
+}
+
+func memoryKey(kind string, key string) string {
+	return kind + ":" + key
+}
+
+// LockedUntil returns the latest lockout end of the username and the client IP
+func (limiter *MemoryLimiter) LockedUntil(ctx context.Context, username string, clientIP string) (time.Time, error) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	var lockedUntil time.Time
+	for _, key := range []string{memoryKey(UsernameKind, username), memoryKey(ClientIPKind, clientIP)} {
+		if a, ok := limiter.attempts[key]; ok && a.lockedUntil.After(lockedUntil) {
+			lockedUntil = a.lockedUntil
+		}
+	}
+
+	if !lockedUntil.After(time.Now()) {
+		return time.Time{}, nil
+	}
+
+	return lockedUntil, nil
+}
+
+// RecordFailure counts the failed attempt for the username and the client IP
+func (limiter *MemoryLimiter) RecordFailure(ctx context.Context, username string, clientIP string) ([]Lockout, error) {
+	lockouts := limiter.recordFailure(username, clientIP)
+
+	if err := auditLockouts(ctx, limiter.store, username, clientIP, lockouts); err != nil {
+		return lockouts, err
+	}
+
+	return lockouts, nil
+}
+
+func (limiter *MemoryLimiter) recordFailure(username string, clientIP string) []Lockout {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	now := time.Now()
+	var lockouts []Lockout
+
+	for _, kv := range [][2]string{{UsernameKind, username}, {ClientIPKind, clientIP}} {
+		key := memoryKey(kv[0], kv[1])
+
+		a, ok := limiter.attempts[key]
+		if !ok && len(limiter.attempts) >= limiter.maxKeys {
+			limiter.evict(now)
+		}
+
+		if !ok || now.Sub(a.lastFailedAt) > limiter.policy.MaxDuration {
+			a = &attempts{}
+			limiter.attempts[key] = a
+		}
+
+		a.failures++
+		a.lastFailedAt = now
+
+		if duration := limiter.policy.lockoutDuration(kv[0], a.failures); duration > 0 {
+			a.lockedUntil = now.Add(duration)
+			lockouts = append(lockouts, Lockout{Kind: kv[0], Key: kv[1], Failures: a.failures, LockedUntil: a.lockedUntil})
+		}
+	}
+
+	return lockouts
+}
+
+// evict makes room for a new key, it drops the forgotten attempts and if that is not enough
+// the key that failed least recently, preferring the keys that are not locked
+// The caller must hold the mutex
+func (limiter *MemoryLimiter) evict(now time.Time) {
+	for key, a := range limiter.attempts {
+		if now.Sub(a.lastFailedAt) > limiter.policy.MaxDuration {
+			delete(limiter.attempts, key)
+		}
+	}
+
+	if len(limiter.attempts) < limiter.maxKeys {
+		return
+	}
+
+	var oldestKey string
+	var oldest *attempts
+	for key, a := range limiter.attempts {
+		locked := a.lockedUntil.After(now)
+		if oldest == nil {
+			oldestKey, oldest = key, a
+			continue
+		}
+
+		oldestLocked := oldest.lockedUntil.After(now)
+		if (oldestLocked && !locked) || (oldestLocked == locked && a.lastFailedAt.Before(oldest.lastFailedAt)) {
+			oldestKey, oldest = key, a
+		}
+	}
+
+	delete(limiter.attempts, oldestKey)
+}
+
+// RecordSuccess forgets the failed attempts of the username
+func (limiter *MemoryLimiter) RecordSuccess(ctx context.Context, username string) error {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	delete(limiter.attempts, memoryKey(UsernameKind, username))
+
+	return nil
+}
+
+// PostgresLimiter keeps the failed attempts in the login_attempts table, so they are shared by all server instances
+type PostgresLimiter struct {
+	store  db.Querier
+	policy Policy
+}
+
+// NewPostgresLimiter returns new limiter backed by the login_attempts table
+func NewPostgresLimiter(store db.Querier, policy Policy) Limiter {
+	return &PostgresLimiter{store: store, policy: policy}
+}
+
+// LockedUntil returns the latest lockout end of the username and the client IP
+func (limiter *PostgresLimiter) LockedUntil(ctx context.Context, username string, clientIP string) (time.Time, error) {
+	loginAttempts, err := limiter.store.ListLoginAttempts(ctx, db.ListLoginAttemptsParams{
+		Username: username,
+		ClientIp: clientIP,
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to list login attempts: %w", err)
+	}
+
+	var lockedUntil time.Time
+	for _, loginAttempt := range loginAttempts {
+		if loginAttempt.LockedUntil.After(lockedUntil) {
+			lockedUntil = loginAttempt.LockedUntil
+		}
+	}
+
+	if !lockedUntil.After(time.Now()) {
+		return time.Time{}, nil
+	}
+
+	return lockedUntil, nil
+}
+
+// RecordFailure counts the failed attempt for the username and the client IP
+func (limiter *PostgresLimiter) RecordFailure(ctx context.Context, username string, clientIP string) ([]Lockout, error) {
+	now := time.Now()
+	var lockouts []Lockout
+
+	for _, kv := range [][2]string{{UsernameKind, username}, {ClientIPKind, clientIP}} {
+		loginAttempt, err := limiter.store.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+			Kind:        kv[0],
+			Key:         kv[1],
+			ResetBefore: now.Add(-limiter.policy.MaxDuration),
+		})
+		if err != nil {
+			return lockouts, fmt.Errorf("failed to record login failure: %w", err)
+		}
+
+		duration := limiter.policy.lockoutDuration(kv[0], loginAttempt.FailedCount)
+		if duration == 0 {
+			continue
+		}
+
+		lockout := Lockout{Kind: kv[0], Key: kv[1], Failures: loginAttempt.FailedCount, LockedUntil: now.Add(duration)}
+
+		err = limiter.store.UpdateLoginLockout(ctx, db.UpdateLoginLockoutParams{
+			Kind:        lockout.Kind,
+			Key:         lockout.Key,
+			LockedUntil: lockout.LockedUntil,
+		})
+		if err != nil {
+			return lockouts, fmt.Errorf("failed to lock login: %w", err)
+		}
+
+		lockouts = append(lockouts, lockout)
+	}
+
+	if err := auditLockouts(ctx, limiter.store, username, clientIP, lockouts); err != nil {
+		return lockouts, err
+	}
+
+	return lockouts, nil
+}
+
+// RecordSuccess forgets the failed attempts of the username
+func (limiter *PostgresLimiter) RecordSuccess(ctx context.Context, username string) error {
+	err := limiter.store.DeleteLoginAttempts(ctx, db.DeleteLoginAttemptsParams{
+		Kind: UsernameKind,
+		Key:  username,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package lockout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+var testPolicy = Policy{
+	MaxUsernameAttempts: 3,
+	MaxClientIPAttempts: 5,
+	BaseDuration:        time.Minute,
+	MaxDuration:         10 * time.Minute,
+}
+
+func TestLockoutDuration(t *testing.T) {
+	testCases := []struct {
+		kind     string
+		failures int32
+		expected time.Duration
+	}{
+		{UsernameKind, 2, 0},
+		{UsernameKind, 3, time.Minute},
+		{UsernameKind, 4, 2 * time.Minute},
+		{UsernameKind, 6, 8 * time.Minute},
+		{UsernameKind, 7, 10 * time.Minute},
+		{UsernameKind, 100, 10 * time.Minute},
+		{ClientIPKind, 4, 0},
+		{ClientIPKind, 5, time.Minute},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, testPolicy.lockoutDuration(tc.kind, tc.failures), "%s after %d failures", tc.kind, tc.failures)
+	}
+}
+
+func TestNewLimiter(t *testing.T) {
+	_, err := NewLimiter("unknown", nil, testPolicy)
+	require.Error(t, err)
+
+	_, err = NewLimiter(MemoryLimiterType, nil, Policy{BaseDuration: time.Minute, MaxDuration: time.Hour})
+	require.Error(t, err)
+
+	invalidDurations := testPolicy
+	invalidDurations.MaxDuration = time.Second
+	_, err = NewLimiter(MemoryLimiterType, nil, invalidDurations)
+	require.Error(t, err)
+
+	limiter, err := NewLimiter(PostgresLimiterType, nil, testPolicy)
+	require.NoError(t, err)
+	require.IsType(t, &PostgresLimiter{}, limiter)
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	username := util.RandomOwner()
+	clientIP := "10.0.0.1"
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateAuditEvent(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateAuditEventParams) (db.AuditEvent, error) {
+			require.Equal(t, LockoutEventType, arg.EventType)
+			require.Equal(t, username, arg.Username)
+			require.Equal(t, clientIP, arg.ClientIp)
+
+			var lockout Lockout
+			require.NoError(t, json.Unmarshal(arg.Details, &lockout))
+			require.Equal(t, UsernameKind, lockout.Kind)
+			require.Equal(t, int32(3), lockout.Failures)
+
+			return db.AuditEvent{ID: 1}, nil
+		})
+
+	limiter := NewMemoryLimiter(store, testPolicy)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		lockouts, err := limiter.RecordFailure(ctx, username, clientIP)
+		require.NoError(t, err)
+		require.Empty(t, lockouts)
+	}
+
+	lockedUntil, err := limiter.LockedUntil(ctx, username, clientIP)
+	require.NoError(t, err)
+	require.True(t, lockedUntil.IsZero())
+
+	lockouts, err := limiter.RecordFailure(ctx, username, clientIP)
+	require.NoError(t, err)
+	require.Len(t, lockouts, 1)
+
+	// the username is locked from any client IP
+	lockedUntil, err = limiter.LockedUntil(ctx, username, "10.0.0.2")
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(time.Minute), lockedUntil, time.Second)
+
+	// the client IP is still below its limit
+	lockedUntil, err = limiter.LockedUntil(ctx, util.RandomOwner(), clientIP)
+	require.NoError(t, err)
+	require.True(t, lockedUntil.IsZero())
+
+	require.NoError(t, limiter.RecordSuccess(ctx, username))
+
+	lockedUntil, err = limiter.LockedUntil(ctx, username, clientIP)
+	require.NoError(t, err)
+	require.True(t, lockedUntil.IsZero())
+}
+
+func TestMemoryLimiterEviction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).Times(1).Return(db.AuditEvent{ID: 1}, nil)
+
+	limiter := NewMemoryLimiter(store, testPolicy).(*MemoryLimiter)
+	limiter.maxKeys = 4
+	ctx := context.Background()
+
+	// the locked username and its client IP fill two of the keys
+	lockedUsername := util.RandomOwner()
+	for i := 0; i < int(testPolicy.MaxUsernameAttempts); i++ {
+		_, err := limiter.RecordFailure(ctx, lockedUsername, "10.0.0.1")
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < 10; i++ {
+		_, err := limiter.RecordFailure(ctx, util.RandomOwner(), fmt.Sprintf("10.0.1.%d", i))
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(limiter.attempts), limiter.maxKeys)
+	}
+
+	// the keys that are not locked are evicted first
+	lockedUntil, err := limiter.LockedUntil(ctx, lockedUsername, "10.0.0.2")
+	require.NoError(t, err)
+	require.False(t, lockedUntil.IsZero())
+}
+
+func TestPostgresLimiter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	username := util.RandomOwner()
+	clientIP := "10.0.0.1"
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		RecordLoginFailure(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, arg db.RecordLoginFailureParams) (db.LoginAttempt, error) {
+			require.WithinDuration(t, time.Now().Add(-testPolicy.MaxDuration), arg.ResetBefore, time.Second)
+
+			if arg.Kind == UsernameKind {
+				return db.LoginAttempt{Kind: arg.Kind, Key: arg.Key, FailedCount: 4}, nil
+			}
+
+			return db.LoginAttempt{Kind: arg.Kind, Key: arg.Key, FailedCount: 1}, nil
+		})
+	store.EXPECT().
+		UpdateLoginLockout(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.UpdateLoginLockoutParams) error {
+			require.Equal(t, UsernameKind, arg.Kind)
+			require.Equal(t, username, arg.Key)
+			require.WithinDuration(t, time.Now().Add(2*time.Minute), arg.LockedUntil, time.Second)
+
+			return nil
+		})
+	store.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).Times(1).Return(db.AuditEvent{ID: 1}, nil)
+
+	limiter := NewPostgresLimiter(store, testPolicy)
+
+	lockouts, err := limiter.RecordFailure(context.Background(), username, clientIP)
+	require.NoError(t, err)
+	require.Len(t, lockouts, 1)
+	require.Equal(t, int32(4), lockouts[0].Failures)
+
+	lockedUntil := time.Now().Add(time.Minute).Truncate(time.Second)
+	store.EXPECT().
+		ListLoginAttempts(gomock.Any(), gomock.Eq(db.ListLoginAttemptsParams{Username: username, ClientIp: clientIP})).
+		Times(1).
+		Return([]db.LoginAttempt{
+			{Kind: UsernameKind, Key: username, LockedUntil: lockedUntil},
+			{Kind: ClientIPKind, Key: clientIP, LockedUntil: time.Now().Add(-time.Minute)},
+		}, nil)
+
+	result, err := limiter.LockedUntil(context.Background(), username, clientIP)
+	require.NoError(t, err)
+	require.Equal(t, lockedUntil, result)
+
+	store.EXPECT().
+		DeleteLoginAttempts(gomock.Any(), gomock.Eq(db.DeleteLoginAttemptsParams{Kind: UsernameKind, Key: username})).
+		Times(1).
+		Return(nil)
+
+	require.NoError(t, limiter.RecordSuccess(context.Background(), username))
+}
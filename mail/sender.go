@@ -0,0 +1,148 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+// Constants for the supported email sender types
+const (
+	SMTPSenderType = "smtp"
+	FileSenderType = "file"
+	LogSenderType  = "log"
+)
+
+// EmailSender sends emails to the users
+type EmailSender interface {
+	// SendEmail sends the HTML content with the subject to the recipients
+	SendEmail(subject string, content string, to []string) error
+}
+
+// NewEmailSender returns new email sender of the configured type
+func NewEmailSender(config util.Config) (EmailSender, error) {
+	switch config.EmailSenderType {
+	case SMTPSenderType:
+		return NewSMTPSender(config.EmailFromAddress, config.SMTPAddress, config.SMTPUsername, config.SMTPPassword)
+	case FileSenderType:
+		return NewFileSender(config.EmailFromAddress, config.EmailOutboxDir)
+	case LogSenderType:
+		return NewLogSender(config.EmailFromAddress), nil
+	default:
+		return nil, fmt.Errorf("unsupported email sender type: %s", config.EmailSenderType)
+	}
+}
+
+// SMTPSender sends the emails through an SMTP server
+type SMTPSender struct {
+	fromAddress string
+	address     string
+	auth        smtp.Auth
+}
+
+// NewSMTPSender returns new SMTP sender, the auth is skipped when the username is empty
+func NewSMTPSender(fromAddress string, address string, username string, password string) (EmailSender, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address: %w", err)
+	}
+
+	sender := &SMTPSender{
+		fromAddress: fromAddress,
+		address:     address,
+	}
+
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return sender, nil
+}
+
+// SendEmail sends the email through the SMTP server
+func (sender *SMTPSender) SendEmail(subject string, content string, to []string) error {
+	message := buildMessage(sender.fromAddress, subject, content, to)
+
+	if err := smtp.SendMail(sender.address, sender.auth, sender.fromAddress, to, message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// FileSender writes every email to a separate file in the outbox directory
+// It is meant for local development, where no SMTP server is available
+type FileSender struct {
+	fromAddress string
+	outboxDir   string
+}
+
+// NewFileSender returns new file sender and creates the outbox directory
+func NewFileSender(fromAddress string, outboxDir string) (EmailSender, error) {
+	if outboxDir == "" {
+		return nil, fmt.Errorf("outbox directory is required")
+	}
+
+	if err := os.MkdirAll(outboxDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	return &FileSender{fromAddress: fromAddress, outboxDir: outboxDir}, nil
+}
+
+// SendEmail writes the email to a new .eml file
+func (sender *FileSender) SendEmail(subject string, content string, to []string) error {
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.New())
+	message := buildMessage(sender.fromAddress, subject, content, to)
+
+	if err := os.WriteFile(filepath.Join(sender.outboxDir, name), message, 0o644); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+
+	return nil
+}
+
+// LogSender only logs the emails
+type LogSender struct {
+	fromAddress string
+}
+
+// NewLogSender returns new log sender
+func NewLogSender(fromAddress string) EmailSender {
+	return &LogSender{fromAddress: fromAddress}
+}
+
+// SendEmail logs the email
+func (sender *LogSender) SendEmail(subject string, content string, to []string) error {
+	log.Info().
+		Str("from", sender.fromAddress).
+		Strs("to", to).
+		Str("subject", subject).
+		Str("content", content).
+		Msg("send email")
+
+	return nil
+}
+
+func buildMessage(fromAddress string, subject string, content string, to []string) []byte {
+	var message bytes.Buffer
+
+	fmt.Fprintf(&message, "From: %s\r\n", fromAddress)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", subject)
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/html; charset=\"UTF-8\"\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(content)
+
+	return message.Bytes()
+}
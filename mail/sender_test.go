@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func TestFileSender(t *testing.T) {
+	outboxDir := filepath.Join(t.TempDir(), "outbox")
+
+	sender, err := NewEmailSender(util.Config{
+		EmailSenderType:  FileSenderType,
+		EmailFromAddress: "no-reply@simplebank.com",
+		EmailOutboxDir:   outboxDir,
+	})
+	require.NoError(t, err)
+
+	err = sender.SendEmail("A test email", "<h1>Hello</h1>", []string{"user@example.com"})
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(outboxDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	message, err := os.ReadFile(filepath.Join(outboxDir, files[0].Name()))
+	require.NoError(t, err)
+	require.Contains(t, string(message), "From: no-reply@simplebank.com\r\n")
+	require.Contains(t, string(message), "To: user@example.com\r\n")
+	require.Contains(t, string(message), "Subject: A test email\r\n")
+	require.Contains(t, string(message), "\r\n\r\n<h1>Hello</h1>")
+}
+
+func TestNewEmailSender(t *testing.T) {
+	_, err := NewEmailSender(util.Config{EmailSenderType: "unknown"})
+	require.Error(t, err)
+
+	_, err = NewEmailSender(util.Config{EmailSenderType: FileSenderType})
+	require.Error(t, err)
+
+	_, err = NewEmailSender(util.Config{EmailSenderType: SMTPSenderType, SMTPAddress: "localhost"})
+	require.Error(t, err)
+
+	sender, err := NewEmailSender(util.Config{EmailSenderType: LogSenderType})
+	require.NoError(t, err)
+	require.NoError(t, sender.SendEmail("subject", "content", []string{"user@example.com"}))
+}
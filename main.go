@@ -4,22 +4,33 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/rakyll/statik/fs"
+	"github.com/rs/zerolog"
 	"github.com/vladoohr/simple_bank/api"
 	db "github.com/vladoohr/simple_bank/db/sqlc"
 	_ "github.com/vladoohr/simple_bank/doc/statik"
 	"github.com/vladoohr/simple_bank/gapi"
+	"github.com/vladoohr/simple_bank/mail"
 	"github.com/vladoohr/simple_bank/pb"
+	"github.com/vladoohr/simple_bank/reconcile"
 	"github.com/vladoohr/simple_bank/util"
+	"github.com/vladoohr/simple_bank/worker"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 
 	_ "github.com/lib/pq"
@@ -31,19 +42,65 @@ func main() {
 		log.Fatal("cannot load the configuration:", err)
 	}
 
+	logLevel, err := zerolog.ParseLevel(config.LogLevel)
+	if err != nil {
+		log.Fatal("invalid log level: ", err)
+	}
+	zerolog.SetGlobalLevel(logLevel)
+
 	conn, err := sql.Open(config.DBDriver, config.DBSource)
 	if err != nil {
 		log.Fatal("cannot connect to db: ", err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		runReconcile(db.NewStore(conn), os.Args[2:])
+		return
+	}
+
+	if !util.IsSupportedServerMode(config.ServerMode) {
+		log.Fatalf("unsupported server mode: %s", config.ServerMode)
+	}
+
 	// run migration
 	runDBMigration(config.MigrationURL, config.DBSource)
 
 	store := db.NewStore(conn)
 
-	go runGrpcServer(config, store)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	wg := &sync.WaitGroup{}
+
+	// the task worker runs next to every server mode, the tasks are enqueued in the same database
+	wg.Add(1)
+	go runTaskProcessor(ctx, wg, config, store)
+
+	switch config.ServerMode {
+	case util.ServerModeGin:
+		wg.Add(1)
+		go runGinServer(ctx, wg, config, store)
+	case util.ServerModeGRPC:
+		wg.Add(1)
+		go runGrpcServer(ctx, wg, config, store)
+	case util.ServerModeGateway:
+		wg.Add(1)
+		go runGatewayServer(ctx, wg, config, store)
+	case util.ServerModeAll:
+		// the gin server and the gateway share the HTTP address, so only the gateway is started
+		wg.Add(2)
+		go runGrpcServer(ctx, wg, config, store)
+		go runGatewayServer(ctx, wg, config, store)
+	}
+
+	wg.Wait()
 
-	runGatewayServer(config, store)
+	err = conn.Close()
+	if err != nil {
+		log.Fatal("failed to close db connection: ", err)
+	}
+
+	log.Println("servers stopped")
 }
 
 func runDBMigration(url, dbSource string) {
@@ -61,19 +118,68 @@ func runDBMigration(url, dbSource string) {
 	log.Println("Migrations run successfully")
 }
 
-func runGatewayServer(config util.Config, store db.Store) {
+// driftExitCode is returned by the reconcile command when -fail-on-drift is set and drift is found
+const driftExitCode = 2
+
+func runReconcile(store db.Store, args []string) {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	format := flags.String("format", reconcile.FormatJSON, "report format: json or csv")
+	batchSize := flags.Int("batch-size", 1000, "number of accounts read per query")
+	failOnDrift := flags.Bool("fail-on-drift", false, "exit with non-zero code if any account has drift")
+	flags.Parse(args)
+
+	reconciler, err := reconcile.NewReconciler(store, int32(*batchSize))
+	if err != nil {
+		log.Fatal("cannot create reconciler: ", err)
+	}
+
+	drifts, err := reconciler.Run(context.Background())
+	if err != nil {
+		log.Fatal("failed to reconcile accounts: ", err)
+	}
+
+	err = reconcile.WriteReport(os.Stdout, *format, drifts)
+	if err != nil {
+		log.Fatal("failed to write reconcile report: ", err)
+	}
+
+	log.Printf("reconciled accounts, %d with drift", len(drifts))
+
+	if *failOnDrift && len(drifts) > 0 {
+		os.Exit(driftExitCode)
+	}
+}
+
+func runTaskProcessor(ctx context.Context, wg *sync.WaitGroup, config util.Config, store db.Store) {
+	defer wg.Done()
+
+	sender, err := mail.NewEmailSender(config)
+	if err != nil {
+		log.Fatal("cannot create email sender: ", err)
+	}
+
+	processor := worker.NewTaskProcessor(config, store, sender)
+
+	log.Println("start task processor")
+
+	processor.Start(ctx)
+
+	log.Println("task processor stopped")
+}
+
+func runGatewayServer(ctx context.Context, wg *sync.WaitGroup, config util.Config, store db.Store) {
+	defer wg.Done()
+
 	server, err := gapi.NewServer(config, store)
 	if err != nil {
 		log.Fatal("cannot create new grpc server: ", err)
 	}
 
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	grpcMux := runtime.NewServeMux(runtime.WithIncomingHeaderMatcher(gapi.HeaderMatcher))
 
-	grpcMux := runtime.NewServeMux()
-
-	err = pb.RegisterSimpleBankHandlerServer(ctx, grpcMux, server)
+	// the gateway proxies the requests to the gRPC server, so they pass through its interceptors
+	dialOptions := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	err = pb.RegisterSimpleBankHandlerFromEndpoint(ctx, grpcMux, config.GRPCServerAddress, dialOptions)
 	if err != nil {
 		log.Fatal("failed to register handler server", err)
 	}
@@ -89,35 +195,33 @@ func runGatewayServer(config util.Config, store db.Store) {
 	swaggerHandler := http.StripPrefix("/swagger/", http.FileServer(statikFS))
 	mux.Handle("/swagger/", swaggerHandler)
 
-	listener, err := net.Listen("tcp", "localhost:8080")
-	if err != nil {
-		log.Fatalf("failed to create listener: %v", err)
-	}
-
-	log.Printf("start HTTP Gateway server on: %s", listener.Addr().String())
-
-	err = http.Serve(listener, mux)
-	if err != nil {
-		log.Fatal("failed to start HTTP Gateway server: ", err)
-	}
+	serveHTTP(ctx, "HTTP Gateway", config.HTTPServerAddress, server.HTTPLogger(mux), config.ShutdownTimeout)
 }
 
-func runGrpcServer(config util.Config, store db.Store) {
+func runGrpcServer(ctx context.Context, wg *sync.WaitGroup, config util.Config, store db.Store) {
+	defer wg.Done()
+
 	server, err := gapi.NewServer(config, store)
 	if err != nil {
 		log.Fatal("cannot create new grpc server: ", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(server.GrpcLogger, server.AuthInterceptor))
 	pb.RegisterSimpleBankServer(grpcServer, server)
 
 	reflection.Register(grpcServer)
 
-	listener, err := net.Listen("tcp", "localhost:9090")
+	listener, err := net.Listen("tcp", config.GRPCServerAddress)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
+	go func() {
+		<-ctx.Done()
+		log.Println("shutting down gRPC server")
+		gracefulStopGrpcServer(grpcServer, config.ShutdownTimeout)
+	}()
+
 	log.Printf("start gRPC server at: %s", listener.Addr().String())
 
 	err = grpcServer.Serve(listener)
@@ -125,16 +229,71 @@ func runGrpcServer(config util.Config, store db.Store) {
 		log.Fatal("failed to start gRPC server: ", err)
 	}
 
+	log.Println("gRPC server stopped")
+}
+
+// gracefulStopGrpcServer waits for the in-flight RPCs to finish
+// and force stops the server if they don't finish within the timeout
+func gracefulStopGrpcServer(grpcServer *grpc.Server, timeout time.Duration) {
+	stopped := make(chan struct{})
+
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+	case <-timer.C:
+		log.Println("gRPC graceful stop timed out, force stopping")
+		grpcServer.Stop()
+	}
 }
 
-func runGinServer(config util.Config, store db.Store) {
+func runGinServer(ctx context.Context, wg *sync.WaitGroup, config util.Config, store db.Store) {
+	defer wg.Done()
+
 	server, err := api.NewServer(config, store)
 	if err != nil {
 		log.Fatal("cannot create new http server: ", err)
 	}
 
-	err = server.Start(config.ServerAddress)
+	serveHTTP(ctx, "Gin HTTP", config.HTTPServerAddress, server.Handler(), config.ShutdownTimeout)
+}
+
+// serveHTTP serves the handler on the address until the context is cancelled
+// and then waits up to the timeout for the in-flight requests to finish
+func serveHTTP(ctx context.Context, name string, address string, handler http.Handler, timeout time.Duration) {
+	listener, err := net.Listen("tcp", address)
 	if err != nil {
-		log.Fatal("cannot start the server: ", err)
+		log.Fatalf("failed to create listener: %v", err)
 	}
+
+	httpServer := &http.Server{Handler: handler}
+
+	go func() {
+		<-ctx.Done()
+		log.Printf("shutting down %s server", name)
+
+		// the parent context is already cancelled, so a fresh one bounds the shutdown
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		err := httpServer.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Printf("failed to shut down %s server gracefully: %v", name, err)
+		}
+	}()
+
+	log.Printf("start %s server on: %s", name, listener.Addr().String())
+
+	err = httpServer.Serve(listener)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("failed to start %s server: %v", name, err)
+	}
+
+	log.Printf("%s server stopped", name)
 }
@@ -0,0 +1,212 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v3.6.1
+// source: rpc_block_session.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type BlockSessionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SessionId string `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+}
+
+func (x *BlockSessionRequest) Reset() {
+	*x = BlockSessionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_block_session_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockSessionRequest) ProtoMessage() {}
+
+func (x *BlockSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_block_session_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockSessionRequest.ProtoReflect.Descriptor instead.
+func (*BlockSessionRequest) Descriptor() ([]byte, []int) {
+	return file_rpc_block_session_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BlockSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type BlockSessionResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Session *Session `protobuf:"bytes,1,opt,name=session,proto3" json:"session,omitempty"`
+}
+
+func (x *BlockSessionResponse) Reset() {
+	*x = BlockSessionResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_rpc_block_session_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockSessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockSessionResponse) ProtoMessage() {}
+
+func (x *BlockSessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_rpc_block_session_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockSessionResponse.ProtoReflect.Descriptor instead.
+func (*BlockSessionResponse) Descriptor() ([]byte, []int) {
+	return file_rpc_block_session_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BlockSessionResponse) GetSession() *Session {
+	if x != nil {
+		return x.Session
+	}
+	return nil
+}
+
+var File_rpc_block_session_proto protoreflect.FileDescriptor
+
+var file_rpc_block_session_proto_rawDesc = []byte{
+	0x0a, 0x17, 0x72, 0x70, 0x63, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x73, 0x65, 0x73, 0x73,
+	0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x1a, 0x0d, 0x73,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x34, 0x0a, 0x13,
+	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x49, 0x64, 0x22, 0x3d, 0x0a, 0x14, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x53, 0x65, 0x73, 0x73, 0x69,
+	0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x25, 0x0a, 0x07, 0x73, 0x65,
+	0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x70, 0x62,
+	0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f,
+	0x6e, 0x42, 0x24, 0x5a, 0x22, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x76, 0x6c, 0x61, 0x64, 0x6f, 0x6f, 0x68, 0x72, 0x2f, 0x73, 0x69, 0x6d, 0x70, 0x6c, 0x65, 0x5f,
+	0x62, 0x61, 0x6e, 0x6b, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_rpc_block_session_proto_rawDescOnce sync.Once
+	file_rpc_block_session_proto_rawDescData = file_rpc_block_session_proto_rawDesc
+)
+
+func file_rpc_block_session_proto_rawDescGZIP() []byte {
+	file_rpc_block_session_proto_rawDescOnce.Do(func() {
+		file_rpc_block_session_proto_rawDescData = protoimpl.X.CompressGZIP(file_rpc_block_session_proto_rawDescData)
+	})
+	return file_rpc_block_session_proto_rawDescData
+}
+
+var file_rpc_block_session_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_rpc_block_session_proto_goTypes = []interface{}{
+	(*BlockSessionRequest)(nil),  // 0: pb.BlockSessionRequest
+	(*BlockSessionResponse)(nil), // 1: pb.BlockSessionResponse
+	(*Session)(nil),              // 2: pb.Session
+}
+var file_rpc_block_session_proto_depIdxs = []int32{
+	2, // 0: pb.BlockSessionResponse.session:type_name -> pb.Session
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_rpc_block_session_proto_init() }
+func file_rpc_block_session_proto_init() {
+	if File_rpc_block_session_proto != nil {
+		return
+	}
+	file_session_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_rpc_block_session_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockSessionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_rpc_block_session_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockSessionResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_rpc_block_session_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_rpc_block_session_proto_goTypes,
+		DependencyIndexes: file_rpc_block_session_proto_depIdxs,
+		MessageInfos:      file_rpc_block_session_proto_msgTypes,
+	}.Build()
+	File_rpc_block_session_proto = out.File
+	file_rpc_block_session_proto_rawDesc = nil
+	file_rpc_block_session_proto_goTypes = nil
+	file_rpc_block_session_proto_depIdxs = nil
+}
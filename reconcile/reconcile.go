@@ -0,0 +1,120 @@
+package reconcile
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Drift describes an account whose balance differs from the sum of its entries
+type Drift struct {
+	AccountID      int64  `json:"account_id"`
+	Owner          string `json:"owner"`
+	Currency       string `json:"currency"`
+	Balance        int64  `json:"balance"`
+	EntriesBalance int64  `json:"entries_balance"`
+	Drift          int64  `json:"drift"`
+}
+
+// Reconciler scans all accounts and compares their balance against the entries ledger
+type Reconciler struct {
+	store     db.Store
+	batchSize int32
+}
+
+// NewReconciler returns new reconciler that reads the accounts in batches of the given size
+func NewReconciler(store db.Store, batchSize int32) (*Reconciler, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size: %d", batchSize)
+	}
+
+	return &Reconciler{
+		store:     store,
+		batchSize: batchSize,
+	}, nil
+}
+
+// Run returns the drift of every account whose balance does not match its entries
+func (reconciler *Reconciler) Run(ctx context.Context) ([]Drift, error) {
+	drifts := []Drift{}
+
+	var afterID int64
+	for {
+		rows, err := reconciler.store.ListAccountBalancesAfter(ctx, db.ListAccountBalancesAfterParams{
+			AfterID:   afterID,
+			LimitSize: reconciler.batchSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list account balances after [%d]: %w", afterID, err)
+		}
+
+		for _, row := range rows {
+			if row.Balance != row.EntriesBalance {
+				drifts = append(drifts, Drift{
+					AccountID:      row.ID,
+					Owner:          row.Owner,
+					Currency:       row.Currency,
+					Balance:        row.Balance,
+					EntriesBalance: row.EntriesBalance,
+					Drift:          row.Balance - row.EntriesBalance,
+				})
+			}
+		}
+
+		if len(rows) < int(reconciler.batchSize) {
+			return drifts, nil
+		}
+
+		afterID = rows[len(rows)-1].ID
+	}
+}
+
+// WriteReport writes the drifts to w in the given format
+func WriteReport(w io.Writer, format string, drifts []Drift) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(drifts)
+	case FormatCSV:
+		return writeCSV(w, drifts)
+	default:
+		return fmt.Errorf("unsupported report format: %s", format)
+	}
+}
+
+func writeCSV(w io.Writer, drifts []Drift) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{"account_id", "owner", "currency", "balance", "entries_balance", "drift"})
+	if err != nil {
+		return err
+	}
+
+	for _, drift := range drifts {
+		err = writer.Write([]string{
+			strconv.FormatInt(drift.AccountID, 10),
+			drift.Owner,
+			drift.Currency,
+			strconv.FormatInt(drift.Balance, 10),
+			strconv.FormatInt(drift.EntriesBalance, 10),
+			strconv.FormatInt(drift.Drift, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,114 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func randomBalanceRow(id int64, drift int64) db.ListAccountBalancesAfterRow {
+	balance := util.RandomBalance()
+
+	return db.ListAccountBalancesAfterRow{
+		ID:             id,
+		Owner:          util.RandomOwner(),
+		Currency:       util.RandomCurrency(),
+		Balance:        balance,
+		EntriesBalance: balance - drift,
+	}
+}
+
+func TestRun(t *testing.T) {
+	batch1 := []db.ListAccountBalancesAfterRow{randomBalanceRow(1, 0), randomBalanceRow(2, 10)}
+	batch2 := []db.ListAccountBalancesAfterRow{randomBalanceRow(3, -5)}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	gomock.InOrder(
+		store.EXPECT().
+			ListAccountBalancesAfter(gomock.Any(), gomock.Eq(db.ListAccountBalancesAfterParams{AfterID: 0, LimitSize: 2})).
+			Times(1).
+			Return(batch1, nil),
+		store.EXPECT().
+			ListAccountBalancesAfter(gomock.Any(), gomock.Eq(db.ListAccountBalancesAfterParams{AfterID: 2, LimitSize: 2})).
+			Times(1).
+			Return(batch2, nil),
+	)
+
+	reconciler, err := NewReconciler(store, 2)
+	require.NoError(t, err)
+
+	drifts, err := reconciler.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, drifts, 2)
+
+	require.Equal(t, int64(2), drifts[0].AccountID)
+	require.Equal(t, int64(10), drifts[0].Drift)
+	require.Equal(t, batch1[1].Balance, drifts[0].Balance)
+	require.Equal(t, batch1[1].EntriesBalance, drifts[0].EntriesBalance)
+
+	require.Equal(t, int64(3), drifts[1].AccountID)
+	require.Equal(t, int64(-5), drifts[1].Drift)
+}
+
+func TestRunStoreError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListAccountBalancesAfter(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, sql.ErrConnDone)
+
+	reconciler, err := NewReconciler(store, 10)
+	require.NoError(t, err)
+
+	_, err = reconciler.Run(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
+
+func TestNewReconcilerInvalidBatchSize(t *testing.T) {
+	_, err := NewReconciler(nil, 0)
+	require.Error(t, err)
+}
+
+func TestWriteReport(t *testing.T) {
+	drifts := []Drift{
+		{AccountID: 1, Owner: "alice", Currency: util.USD, Balance: 100, EntriesBalance: 90, Drift: 10},
+	}
+
+	var buffer bytes.Buffer
+	err := WriteReport(&buffer, FormatJSON, drifts)
+	require.NoError(t, err)
+
+	var gotDrifts []Drift
+	err = json.Unmarshal(buffer.Bytes(), &gotDrifts)
+	require.NoError(t, err)
+	require.Equal(t, drifts, gotDrifts)
+
+	buffer.Reset()
+	err = WriteReport(&buffer, FormatCSV, drifts)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Equal(t, []string{
+		"account_id,owner,currency,balance,entries_balance,drift",
+		"1,alice,USD,100,90,10",
+	}, lines)
+
+	err = WriteReport(&buffer, "xml", drifts)
+	require.Error(t, err)
+}
@@ -0,0 +1,114 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+)
+
+// Constants for the supported denylist types
+const (
+	MemoryDenylistType   = "memory"
+	PostgresDenylistType = "postgres"
+)
+
+// Denylist holds the revoked sessions, so the tokens tied to them are rejected before they expire
+type Denylist interface {
+	// Add revokes the session until it expires
+	Add(ctx context.Context, sessionID uuid.UUID, expiresAt time.Time) error
+
+	// Contains returns true if the session is revoked
+	Contains(ctx context.Context, sessionID uuid.UUID) (bool, error)
+}
+
+// NewDenylist returns new denylist of the given type
+func NewDenylist(denylistType string, store db.Querier) (Denylist, error) {
+	switch denylistType {
+	case MemoryDenylistType:
+		return NewMemoryDenylist(), nil
+	case PostgresDenylistType:
+		return NewPostgresDenylist(store), nil
+	default:
+		return nil, fmt.Errorf("unsupported denylist type: %s", denylistType)
+	}
+}
+
+// MemoryDenylist keeps the revoked sessions in memory of a single server instance
+type MemoryDenylist struct {
+	mutex    sync.RWMutex
+	sessions map[uuid.UUID]time.Time
+}
+
+// NewMemoryDenylist returns new empty in-memory denylist
+func NewMemoryDenylist() Denylist {
+	return &MemoryDenylist{
+		sessions: make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Add revokes the session and drops the sessions that have already expired
+func (denylist *MemoryDenylist) Add(ctx context.Context, sessionID uuid.UUID, expiresAt time.Time) error {
+	denylist.mutex.Lock()
+	defer denylist.mutex.Unlock()
+
+	now := time.Now()
+	for id, expiry := range denylist.sessions {
+		if now.After(expiry) {
+			delete(denylist.sessions, id)
+		}
+	}
+
+	denylist.sessions[sessionID] = expiresAt
+
+	return nil
+}
+
+// Contains returns true if the session is revoked and not yet expired
+func (denylist *MemoryDenylist) Contains(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	denylist.mutex.RLock()
+	defer denylist.mutex.RUnlock()
+
+	expiresAt, ok := denylist.sessions[sessionID]
+
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+// PostgresDenylist uses the blocked flag of the sessions table, so the revocations are shared by all server instances
+type PostgresDenylist struct {
+	store db.Querier
+}
+
+// NewPostgresDenylist returns new denylist backed by the sessions table
+func NewPostgresDenylist(store db.Querier) Denylist {
+	return &PostgresDenylist{store: store}
+}
+
+// Add blocks the session
+func (denylist *PostgresDenylist) Add(ctx context.Context, sessionID uuid.UUID, expiresAt time.Time) error {
+	_, err := denylist.store.BlockSession(ctx, sessionID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	return nil
+}
+
+// Contains returns true if the session is blocked or does not exist
+func (denylist *PostgresDenylist) Contains(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	session, err := denylist.store.GetSession(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	return session.IsBlocked, nil
+}
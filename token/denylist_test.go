@@ -0,0 +1,94 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+)
+
+func TestMemoryDenylist(t *testing.T) {
+	denylist := NewMemoryDenylist()
+
+	sessionID := uuid.New()
+	expiredSessionID := uuid.New()
+
+	revoked, err := denylist.Contains(context.Background(), sessionID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = denylist.Add(context.Background(), sessionID, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	err = denylist.Add(context.Background(), expiredSessionID, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	revoked, err = denylist.Contains(context.Background(), sessionID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = denylist.Contains(context.Background(), expiredSessionID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestPostgresDenylist(t *testing.T) {
+	sessionID := uuid.New()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	denylist, err := NewDenylist(PostgresDenylistType, store)
+	require.NoError(t, err)
+
+	store.EXPECT().
+		BlockSession(gomock.Any(), gomock.Eq(sessionID)).
+		Times(1).
+		Return(db.Session{ID: sessionID, IsBlocked: true}, nil)
+
+	err = denylist.Add(context.Background(), sessionID, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	gomock.InOrder(
+		store.EXPECT().
+			GetSession(gomock.Any(), gomock.Eq(sessionID)).
+			Times(1).
+			Return(db.Session{ID: sessionID, IsBlocked: true}, nil),
+		store.EXPECT().
+			GetSession(gomock.Any(), gomock.Eq(sessionID)).
+			Times(1).
+			Return(db.Session{}, sql.ErrNoRows),
+		store.EXPECT().
+			GetSession(gomock.Any(), gomock.Eq(sessionID)).
+			Times(1).
+			Return(db.Session{ID: sessionID}, nil),
+	)
+
+	// blocked session
+	revoked, err := denylist.Contains(context.Background(), sessionID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// unknown session
+	revoked, err = denylist.Contains(context.Background(), sessionID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// active session
+	revoked, err = denylist.Contains(context.Background(), sessionID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestNewDenylistUnsupportedType(t *testing.T) {
+	_, err := NewDenylist("redis", nil)
+	require.Error(t, err)
+}
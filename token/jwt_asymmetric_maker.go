@@ -0,0 +1,135 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const minRSAKeyBits = 2048
+
+// JWTAsymmetricMaker signs the token with a private key, so it can be verified with the public key only
+type JWTAsymmetricMaker struct {
+	method     jwt.SigningMethod
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+}
+
+// NewRS256JWTMaker creates a new JWT maker that signs the tokens with RSA keys
+// The private key may be nil, in which case the maker can only verify tokens
+func NewRS256JWTMaker(privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey) (Maker, error) {
+	if publicKey == nil {
+		return nil, errors.New("public key is required")
+	}
+
+	if publicKey.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("invalid key size, must be at least %d bits", minRSAKeyBits)
+	}
+
+	maker := &JWTAsymmetricMaker{
+		method:    jwt.SigningMethodRS256,
+		publicKey: publicKey,
+	}
+
+	if privateKey != nil {
+		if !privateKey.PublicKey.Equal(publicKey) {
+			return nil, errors.New("private key does not match the public key")
+		}
+
+		maker.privateKey = privateKey
+	}
+
+	return maker, nil
+}
+
+// NewEdDSAJWTMaker creates a new JWT maker that signs the tokens with Ed25519 keys
+// The private key may be nil, in which case the maker can only verify tokens
+func NewEdDSAJWTMaker(privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey) (Maker, error) {
+	if err := validateEd25519Keys(privateKey, publicKey); err != nil {
+		return nil, err
+	}
+
+	maker := &JWTAsymmetricMaker{
+		method:    jwt.SigningMethodEdDSA,
+		publicKey: publicKey,
+	}
+
+	if privateKey != nil {
+		maker.privateKey = privateKey
+	}
+
+	return maker, nil
+}
+
+// CreateToken creates new signed token for a given username and role
+func (maker *JWTAsymmetricMaker) CreateToken(username string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	if maker.privateKey == nil {
+		return "", nil, ErrMissingPrivateKey
+	}
+
+	payload, err := NewPayload(username, role, sessionID, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	token := jwt.NewWithClaims(maker.method, payload)
+
+	tokenString, err := token.SignedString(maker.privateKey)
+
+	return tokenString, payload, err
+}
+
+// VerifyToken verifies the token signature and returns the token payload
+func (maker *JWTAsymmetricMaker) VerifyToken(tokenString string) (*Payload, error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != maker.method.Alg() {
+			return nil, ErrInvalidToken
+		}
+
+		return maker.publicKey, nil
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Payload{}, keyFunc)
+	if err != nil {
+		if verr, ok := err.(*jwt.ValidationError); ok {
+			if errors.Is(verr.Inner, ErrExpiredToken) {
+				return nil, ErrExpiredToken
+			}
+		}
+
+		return nil, ErrInvalidToken
+	}
+
+	payload, ok := token.Claims.(*Payload)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return payload, nil
+}
+
+func validateEd25519Keys(privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey) error {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key size, must be exactly %d bytes", ed25519.PublicKeySize)
+	}
+
+	if privateKey == nil {
+		return nil
+	}
+
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid private key size, must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+
+	if !publicKey.Equal(privateKey.Public()) {
+		return errors.New("private key does not match the public key")
+	}
+
+	return nil
+}
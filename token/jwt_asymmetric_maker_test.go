@@ -0,0 +1,135 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func newTestRSAKey(t *testing.T) *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	require.NoError(t, err)
+
+	return privateKey
+}
+
+func TestAsymmetricJWTToken(t *testing.T) {
+	rsaKey := newTestRSAKey(t)
+	edPrivateKey, edPublicKey := newTestEd25519Keys(t)
+
+	testCases := []struct {
+		name        string
+		buildMakers func() (Maker, Maker, error)
+	}{
+		{
+			name: "RS256",
+			buildMakers: func() (Maker, Maker, error) {
+				maker, err := NewRS256JWTMaker(rsaKey, &rsaKey.PublicKey)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				verifier, err := NewRS256JWTMaker(nil, &rsaKey.PublicKey)
+				return maker, verifier, err
+			},
+		},
+		{
+			name: "EdDSA",
+			buildMakers: func() (Maker, Maker, error) {
+				maker, err := NewEdDSAJWTMaker(edPrivateKey, edPublicKey)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				verifier, err := NewEdDSAJWTMaker(nil, edPublicKey)
+				return maker, verifier, err
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			maker, verifier, err := tc.buildMakers()
+			require.NoError(t, err)
+
+			username := util.RandomOwner()
+			role := util.BankerRole
+			sessionID := uuid.New()
+			duration := time.Minute
+			issuedAt := time.Now()
+			expiredAt := issuedAt.Add(duration)
+
+			tokenString, payload, err := maker.CreateToken(username, role, sessionID, duration)
+			require.NoError(t, err)
+			require.NotEmpty(t, tokenString)
+			require.NotEmpty(t, payload)
+
+			payload, err = verifier.VerifyToken(tokenString)
+			require.NoError(t, err)
+			require.NotEmpty(t, payload)
+
+			require.NotZero(t, payload.ID)
+			require.Equal(t, payload.Username, username)
+			require.Equal(t, payload.Role, role)
+			require.Equal(t, payload.SessionID, sessionID)
+			require.WithinDuration(t, payload.ExpireAt, expiredAt, time.Second)
+			require.WithinDuration(t, payload.IssuedAt, issuedAt, time.Second)
+
+			_, _, err = verifier.CreateToken(username, role, sessionID, duration)
+			require.EqualError(t, err, ErrMissingPrivateKey.Error())
+
+			tokenString, _, err = maker.CreateToken(username, role, sessionID, -time.Minute)
+			require.NoError(t, err)
+
+			payload, err = verifier.VerifyToken(tokenString)
+			require.EqualError(t, err, ErrExpiredToken.Error())
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestAsymmetricJWTTokenAlgMismatch(t *testing.T) {
+	rsaKey := newTestRSAKey(t)
+	edPrivateKey, edPublicKey := newTestEd25519Keys(t)
+
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	// A token signed with the Ed25519 key must not pass the RS256 verifier
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, payload).SignedString(edPrivateKey)
+	require.NoError(t, err)
+
+	rsaVerifier, err := NewRS256JWTMaker(nil, &rsaKey.PublicKey)
+	require.NoError(t, err)
+
+	verifiedPayload, err := rsaVerifier.VerifyToken(tokenString)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, verifiedPayload)
+
+	// An unsigned token must not pass the EdDSA verifier
+	tokenString, err = jwt.NewWithClaims(jwt.SigningMethodNone, payload).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	edVerifier, err := NewEdDSAJWTMaker(nil, edPublicKey)
+	require.NoError(t, err)
+
+	verifiedPayload, err = edVerifier.VerifyToken(tokenString)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, verifiedPayload)
+}
+
+func TestRS256JWTMakerKeySize(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	_, err = NewRS256JWTMaker(privateKey, &privateKey.PublicKey)
+	require.Error(t, err)
+}
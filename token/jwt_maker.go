@@ -6,34 +6,55 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 const minSecretKeyLen = 32
 
-// JWTMaker holds the secret key for signing the token
+// JWTMaker holds the secret keys for signing the token
 type JWTMaker struct {
-	secretKey string
+	keyRing *KeyRing
 }
 
 // NewJWTMaker creates a new JWTMaker
 func NewJWTMaker(secretKey string) (Maker, error) {
-	if len(secretKey) < minSecretKeyLen {
-		return nil, fmt.Errorf("Invalid key size, must be at least %d characters", minSecretKeyLen)
+	keyRing, err := NewKeyRing(DefaultKeyID, map[string][]byte{DefaultKeyID: []byte(secretKey)})
+	if err != nil {
+		return nil, err
 	}
 
-	return &JWTMaker{secretKey: secretKey}, nil
+	return NewJWTKeyRingMaker(keyRing)
+}
+
+// NewJWTKeyRingMaker creates a new JWTMaker that signs the tokens with the active key of the ring
+func NewJWTKeyRingMaker(keyRing *KeyRing) (Maker, error) {
+	err := keyRing.validate(func(key []byte) error {
+		if len(key) < minSecretKeyLen {
+			return fmt.Errorf("Invalid key size, must be at least %d characters", minSecretKeyLen)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWTMaker{keyRing: keyRing}, nil
 }
 
 // CreateToken creates new token for a given username and password
-func (jwtMaker *JWTMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(username, duration)
+func (jwtMaker *JWTMaker) CreateToken(username string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, sessionID, duration)
 	if err != nil {
 		return "", payload, err
 	}
 
+	keyID, key := jwtMaker.keyRing.ActiveKey()
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+	token.Header["kid"] = keyID
 
-	tokenString, err := token.SignedString([]byte(jwtMaker.secretKey))
+	tokenString, err := token.SignedString(key)
 
 	return tokenString, payload, err
 }
@@ -45,7 +66,14 @@ func (jwtMaker *JWTMaker) VerifyToken(tokenString string) (*Payload, error) {
 			return nil, ErrInvalidToken
 		}
 
-		return []byte(jwtMaker.secretKey), nil
+		keyID, _ := token.Header["kid"].(string)
+
+		key, ok := jwtMaker.keyRing.Key(keyID)
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+
+		return key, nil
 	}
 
 	token, err := jwt.ParseWithClaims(tokenString, &Payload{}, keyFunc)
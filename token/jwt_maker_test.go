@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"github.com/vladoohr/simple_bank/util"
 )
@@ -19,11 +20,13 @@ func TestJWTToken(t *testing.T) {
 	require.NotEmpty(t, jwtMaker)
 
 	username := util.RandomOwner()
+	role := util.DepositorRole
+	sessionID := uuid.New()
 	duration := time.Minute
 	issuedAt := time.Now()
 	expiredAt := issuedAt.Add(duration)
 
-	tokenString, payload, err := jwtMaker.CreateToken(username, duration)
+	tokenString, payload, err := jwtMaker.CreateToken(username, role, sessionID, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, tokenString)
 	require.NotEmpty(t, payload)
@@ -34,6 +37,8 @@ func TestJWTToken(t *testing.T) {
 
 	require.NotZero(t, payload.ID)
 	require.Equal(t, payload.Username, username)
+	require.Equal(t, payload.Role, role)
+	require.Equal(t, payload.SessionID, sessionID)
 	require.WithinDuration(t, payload.ExpireAt, expiredAt, time.Second)
 	require.WithinDuration(t, payload.IssuedAt, issuedAt, time.Second)
 }
@@ -44,9 +49,11 @@ func TestExpiredJWTToken(t *testing.T) {
 	require.NotEmpty(t, jwtMaker)
 
 	username := util.RandomOwner()
+	role := util.DepositorRole
+	sessionID := uuid.New()
 	duration := -time.Minute
 
-	tokenString, payload, err := jwtMaker.CreateToken(username, duration)
+	tokenString, payload, err := jwtMaker.CreateToken(username, role, sessionID, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, tokenString)
 	require.NotEmpty(t, payload)
@@ -58,9 +65,11 @@ func TestExpiredJWTToken(t *testing.T) {
 
 func TestInvalidJWTTokenAlgNone(t *testing.T) {
 	username := util.RandomOwner()
+	role := util.DepositorRole
+	sessionID := uuid.New()
 	duration := -time.Minute
 
-	payload, err := NewPayload(username, duration)
+	payload, err := NewPayload(username, role, sessionID, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
@@ -0,0 +1,90 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultKeyID identifies the single symmetric key when no key ring is configured
+// Tokens without a key ID are verified with this key, so the tokens issued before rotation stay valid
+const DefaultKeyID = "default"
+
+// KeyRing holds the symmetric keys by their ID
+// New tokens are created with the active key, the other keys are accepted for verification until they are removed
+type KeyRing struct {
+	activeKeyID string
+	keys        map[string][]byte
+}
+
+// NewKeyRing returns new key ring and checks that the active key is part of it
+func NewKeyRing(activeKeyID string, keys map[string][]byte) (*KeyRing, error) {
+	if _, ok := keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active key [%s] not found in the key ring", activeKeyID)
+	}
+
+	ring := &KeyRing{
+		activeKeyID: activeKeyID,
+		keys:        make(map[string][]byte, len(keys)),
+	}
+
+	for id, key := range keys {
+		if id == "" {
+			return nil, fmt.Errorf("key ID must not be empty")
+		}
+
+		ring.keys[id] = key
+	}
+
+	return ring, nil
+}
+
+// ParseKeys parses the keys in the "id:key,id:key" format
+func ParseKeys(keys string) (map[string][]byte, error) {
+	parsedKeys := make(map[string][]byte)
+
+	for _, entry := range strings.Split(keys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, key, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid key ring entry, must be in the id:key format")
+		}
+
+		if _, exists := parsedKeys[id]; exists {
+			return nil, fmt.Errorf("duplicate key ID: %s", id)
+		}
+
+		parsedKeys[id] = []byte(key)
+	}
+
+	return parsedKeys, nil
+}
+
+// ActiveKey returns the ID and the value of the key used for new tokens
+func (ring *KeyRing) ActiveKey() (string, []byte) {
+	return ring.activeKeyID, ring.keys[ring.activeKeyID]
+}
+
+// Key returns the key with the given ID, the empty ID resolves to the default key
+func (ring *KeyRing) Key(id string) ([]byte, bool) {
+	if id == "" {
+		id = DefaultKeyID
+	}
+
+	key, ok := ring.keys[id]
+	return key, ok
+}
+
+// validate checks every key in the ring
+func (ring *KeyRing) validate(validateKey func(key []byte) error) error {
+	for id, key := range ring.keys {
+		if err := validateKey(key); err != nil {
+			return fmt.Errorf("key [%s]: %w", id, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/o1egl/paseto"
+	"github.com/stretchr/testify/require"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys(" v1:first-key , v2:second:key,")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"v1": []byte("first-key"), "v2": []byte("second:key")}, keys)
+
+	_, err = ParseKeys("v1")
+	require.Error(t, err)
+
+	_, err = ParseKeys("v1:a,v1:b")
+	require.Error(t, err)
+
+	_, err = NewKeyRing("v3", keys)
+	require.Error(t, err)
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey := []byte(util.RandomString(32))
+	newKey := []byte(util.RandomString(32))
+
+	testCases := []struct {
+		name       string
+		buildMaker func(ring *KeyRing) (Maker, error)
+	}{
+		{
+			name:       "Paseto",
+			buildMaker: NewPasetoKeyRingMaker,
+		},
+		{
+			name:       "JWT",
+			buildMaker: NewJWTKeyRingMaker,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			oldRing, err := NewKeyRing("v1", map[string][]byte{"v1": oldKey})
+			require.NoError(t, err)
+			oldMaker, err := tc.buildMaker(oldRing)
+			require.NoError(t, err)
+
+			oldToken, _, err := oldMaker.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute)
+			require.NoError(t, err)
+
+			// the new key is active and the old one is still accepted
+			rotatedRing, err := NewKeyRing("v2", map[string][]byte{"v1": oldKey, "v2": newKey})
+			require.NoError(t, err)
+			rotatedMaker, err := tc.buildMaker(rotatedRing)
+			require.NoError(t, err)
+
+			payload, err := rotatedMaker.VerifyToken(oldToken)
+			require.NoError(t, err)
+			require.NotEmpty(t, payload)
+
+			newToken, _, err := rotatedMaker.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute)
+			require.NoError(t, err)
+
+			_, err = oldMaker.VerifyToken(newToken)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+
+			// the old key is retired
+			retiredRing, err := NewKeyRing("v2", map[string][]byte{"v2": newKey})
+			require.NoError(t, err)
+			retiredMaker, err := tc.buildMaker(retiredRing)
+			require.NoError(t, err)
+
+			_, err = retiredMaker.VerifyToken(oldToken)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+
+			payload, err = retiredMaker.VerifyToken(newToken)
+			require.NoError(t, err)
+			require.NotEmpty(t, payload)
+		})
+	}
+}
+
+func TestPasetoTokenWithoutKeyID(t *testing.T) {
+	key := []byte(util.RandomString(32))
+
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	// tokens issued before the key ring carry no footer
+	legacyToken, err := paseto.NewV2().Encrypt(key, payload, nil)
+	require.NoError(t, err)
+
+	ring, err := NewKeyRing("v2", map[string][]byte{DefaultKeyID: key, "v2": []byte(util.RandomString(32))})
+	require.NoError(t, err)
+
+	maker, err := NewPasetoKeyRingMaker(ring)
+	require.NoError(t, err)
+
+	verifiedPayload, err := maker.VerifyToken(legacyToken)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, verifiedPayload.ID)
+}
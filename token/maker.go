@@ -1,11 +1,135 @@
 package token
 
-import "time"
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+// Constants for the supported token types
+const (
+	PasetoTokenType       = "paseto"
+	PasetoPublicTokenType = "paseto_public"
+	JWTTokenType          = "jwt"
+	JWTRS256TokenType     = "jwt_rs256"
+	JWTEdDSATokenType     = "jwt_eddsa"
+)
 
 type Maker interface {
-	// CreateToken creates new token for a given username and password
-	CreateToken(username string, duration time.Duration) (string, *Payload, error)
+	// CreateToken creates new token for a given username and role tied to the login session
+	CreateToken(username string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error)
 
 	// VerifyToken verifies the token and returns the token payload
 	VerifyToken(token string) (*Payload, error)
 }
+
+// NewMaker returns new token maker of the configured type
+// Symmetric makers use the symmetric key, asymmetric makers load their keys from the PEM files
+// An asymmetric maker without a private key file can only verify tokens
+func NewMaker(config util.Config) (Maker, error) {
+	switch config.TokenType {
+	case PasetoTokenType, JWTTokenType:
+		keyRing, err := symmetricKeyRing(config)
+		if err != nil {
+			return nil, err
+		}
+
+		if config.TokenType == PasetoTokenType {
+			return NewPasetoKeyRingMaker(keyRing)
+		}
+
+		return NewJWTKeyRingMaker(keyRing)
+	case PasetoPublicTokenType, JWTEdDSATokenType:
+		privateKey, publicKey, err := loadKeyPair(config.TokenPrivateKeyFile, config.TokenPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		edPublicKey, ok := publicKey.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s token requires Ed25519 public key", config.TokenType)
+		}
+
+		var edPrivateKey ed25519.PrivateKey
+		if privateKey != nil {
+			if edPrivateKey, ok = privateKey.(ed25519.PrivateKey); !ok {
+				return nil, fmt.Errorf("%s token requires Ed25519 private key", config.TokenType)
+			}
+		}
+
+		if config.TokenType == PasetoPublicTokenType {
+			return NewPasetoPublicMaker(edPrivateKey, edPublicKey)
+		}
+
+		return NewEdDSAJWTMaker(edPrivateKey, edPublicKey)
+	case JWTRS256TokenType:
+		privateKey, publicKey, err := loadKeyPair(config.TokenPrivateKeyFile, config.TokenPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		rsaPublicKey, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s token requires RSA public key", config.TokenType)
+		}
+
+		var rsaPrivateKey *rsa.PrivateKey
+		if privateKey != nil {
+			if rsaPrivateKey, ok = privateKey.(*rsa.PrivateKey); !ok {
+				return nil, fmt.Errorf("%s token requires RSA private key", config.TokenType)
+			}
+		}
+
+		return NewRS256JWTMaker(rsaPrivateKey, rsaPublicKey)
+	default:
+		return nil, fmt.Errorf("unsupported token type: %s", config.TokenType)
+	}
+}
+
+// symmetricKeyRing builds the key ring from the configured keys
+// The single symmetric key is kept under the default ID, so the tokens issued with it stay valid after rotation
+func symmetricKeyRing(config util.Config) (*KeyRing, error) {
+	keys, err := ParseKeys(config.TokenSymmetricKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := keys[DefaultKeyID]; !ok && config.TokenSymmetricKey != "" {
+		keys[DefaultKeyID] = []byte(config.TokenSymmetricKey)
+	}
+
+	activeKeyID := config.TokenActiveKeyID
+	if activeKeyID == "" {
+		activeKeyID = DefaultKeyID
+	}
+
+	return NewKeyRing(activeKeyID, keys)
+}
+
+// loadKeyPair loads the public key and, when the file is configured, the private key
+func loadKeyPair(privateKeyFile string, publicKeyFile string) (crypto.PrivateKey, crypto.PublicKey, error) {
+	if publicKeyFile == "" {
+		return nil, nil, fmt.Errorf("public key file is required")
+	}
+
+	publicKey, err := LoadPublicKey(publicKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load public key: %w", err)
+	}
+
+	if privateKeyFile == "" {
+		return nil, publicKey, nil
+	}
+
+	privateKey, err := LoadPrivateKey(privateKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load private key: %w", err)
+	}
+
+	return privateKey, publicKey, nil
+}
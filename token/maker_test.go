@@ -0,0 +1,116 @@
+package token
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func writePEMFile(t *testing.T, name string, blockType string, data []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600)
+	require.NoError(t, err)
+
+	return path
+}
+
+func TestNewMaker(t *testing.T) {
+	edPrivateKey, edPublicKey := newTestEd25519Keys(t)
+	rsaKey := newTestRSAKey(t)
+
+	edPrivateDER, err := x509.MarshalPKCS8PrivateKey(edPrivateKey)
+	require.NoError(t, err)
+	edPublicDER, err := x509.MarshalPKIXPublicKey(edPublicKey)
+	require.NoError(t, err)
+	rsaPublicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+
+	edPrivateKeyFile := writePEMFile(t, "ed25519.pem", "PRIVATE KEY", edPrivateDER)
+	edPublicKeyFile := writePEMFile(t, "ed25519.pub.pem", "PUBLIC KEY", edPublicDER)
+	rsaPrivateKeyFile := writePEMFile(t, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	rsaPublicKeyFile := writePEMFile(t, "rsa.pub.pem", "PUBLIC KEY", rsaPublicDER)
+
+	testCases := []struct {
+		name      string
+		config    util.Config
+		canCreate bool
+		expectErr bool
+	}{
+		{
+			name:      "Paseto",
+			config:    util.Config{TokenType: PasetoTokenType, TokenSymmetricKey: util.RandomString(32)},
+			canCreate: true,
+		},
+		{
+			name:      "JWT",
+			config:    util.Config{TokenType: JWTTokenType, TokenSymmetricKey: util.RandomString(32)},
+			canCreate: true,
+		},
+		{
+			name:      "PasetoPublic",
+			config:    util.Config{TokenType: PasetoPublicTokenType, TokenPrivateKeyFile: edPrivateKeyFile, TokenPublicKeyFile: edPublicKeyFile},
+			canCreate: true,
+		},
+		{
+			name:      "JWTEdDSA",
+			config:    util.Config{TokenType: JWTEdDSATokenType, TokenPrivateKeyFile: edPrivateKeyFile, TokenPublicKeyFile: edPublicKeyFile},
+			canCreate: true,
+		},
+		{
+			name:      "JWTRS256",
+			config:    util.Config{TokenType: JWTRS256TokenType, TokenPrivateKeyFile: rsaPrivateKeyFile, TokenPublicKeyFile: rsaPublicKeyFile},
+			canCreate: true,
+		},
+		{
+			name:   "VerifyOnly",
+			config: util.Config{TokenType: PasetoPublicTokenType, TokenPublicKeyFile: edPublicKeyFile},
+		},
+		{
+			name:      "KeyTypeMismatch",
+			config:    util.Config{TokenType: JWTRS256TokenType, TokenPublicKeyFile: edPublicKeyFile},
+			expectErr: true,
+		},
+		{
+			name:      "MissingPublicKey",
+			config:    util.Config{TokenType: JWTEdDSATokenType, TokenPrivateKeyFile: edPrivateKeyFile},
+			expectErr: true,
+		},
+		{
+			name:      "UnsupportedType",
+			config:    util.Config{TokenType: "unknown"},
+			expectErr: true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := NewMaker(tc.config)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			tokenString, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute)
+			if !tc.canCreate {
+				require.EqualError(t, err, ErrMissingPrivateKey.Error())
+				return
+			}
+			require.NoError(t, err)
+
+			payload, err := maker.VerifyToken(tokenString)
+			require.NoError(t, err)
+			require.NotEmpty(t, payload)
+		})
+	}
+}
@@ -4,38 +4,63 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/o1egl/paseto"
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
 // PasetoMaker is a PASETO token maker
 type PasetoMaker struct {
-	paseto      *paseto.V2
-	symetricKey []byte
+	paseto  *paseto.V2
+	keyRing *KeyRing
+}
+
+// pasetoFooter is stored unencrypted in the token and tells which key encrypted it
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
 }
 
 // NewPasetoMaker valdiates the len of the key and returns new instance of PasetoMaker
 func NewPasetoMaker(symetricKey []byte) (Maker, error) {
-	if len(symetricKey) != chacha20poly1305.KeySize {
-		return nil, fmt.Errorf("invalid key size, must be exactly %d characters", chacha20poly1305.KeySize)
+	keyRing, err := NewKeyRing(DefaultKeyID, map[string][]byte{DefaultKeyID: symetricKey})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPasetoKeyRingMaker(keyRing)
+}
+
+// NewPasetoKeyRingMaker valdiates the len of every key in the ring and returns new instance of PasetoMaker
+func NewPasetoKeyRingMaker(keyRing *KeyRing) (Maker, error) {
+	err := keyRing.validate(func(key []byte) error {
+		if len(key) != chacha20poly1305.KeySize {
+			return fmt.Errorf("invalid key size, must be exactly %d characters", chacha20poly1305.KeySize)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	maker := &PasetoMaker{
-		paseto:      paseto.NewV2(),
-		symetricKey: symetricKey,
+		paseto:  paseto.NewV2(),
+		keyRing: keyRing,
 	}
 
 	return maker, nil
 }
 
 // CreateToken creates new token for a given username and password
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, *Payload, error) {
-	payload, err := NewPayload(username, duration)
+func (maker *PasetoMaker) CreateToken(username string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, sessionID, duration)
 	if err != nil {
 		return "", nil, err
 	}
 
-	token, err := maker.paseto.Encrypt(maker.symetricKey, payload, nil)
+	keyID, key := maker.keyRing.ActiveKey()
+
+	token, err := maker.paseto.Encrypt(key, payload, pasetoFooter{KeyID: keyID})
 	if err != nil {
 		return "", nil, err
 	}
@@ -45,9 +70,19 @@ func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (
 
 // VerifyToken verifies the token and returns the token payload
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
+	footer := pasetoFooter{}
+	if err := paseto.ParseFooter(token, &footer); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, ok := maker.keyRing.Key(footer.KeyID)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
 	payload := &Payload{}
 
-	if err := maker.paseto.Decrypt(token, maker.symetricKey, payload, nil); err != nil {
+	if err := maker.paseto.Decrypt(token, key, payload, nil); err != nil {
 		return nil, ErrInvalidToken
 	}
 
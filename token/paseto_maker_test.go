@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"github.com/vladoohr/simple_bank/util"
 )
@@ -14,11 +15,13 @@ func TestPasetoToken(t *testing.T) {
 	require.NotEmpty(t, pasetoMaker)
 
 	username := util.RandomOwner()
+	role := util.DepositorRole
+	sessionID := uuid.New()
 	duration := time.Minute
 	issuedAt := time.Now()
 	expiredAt := issuedAt.Add(duration)
 
-	tokenString, payload, err := pasetoMaker.CreateToken(username, duration)
+	tokenString, payload, err := pasetoMaker.CreateToken(username, role, sessionID, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, tokenString)
 	require.NotEmpty(t, payload)
@@ -29,6 +32,8 @@ func TestPasetoToken(t *testing.T) {
 
 	require.NotZero(t, payload.ID)
 	require.Equal(t, payload.Username, username)
+	require.Equal(t, payload.Role, role)
+	require.Equal(t, payload.SessionID, sessionID)
 	require.WithinDuration(t, payload.ExpireAt, expiredAt, time.Second)
 	require.WithinDuration(t, payload.IssuedAt, issuedAt, time.Second)
 }
@@ -39,9 +44,11 @@ func TestExpiredPasetoToken(t *testing.T) {
 	require.NotEmpty(t, pasetoMaker)
 
 	username := util.RandomOwner()
+	role := util.DepositorRole
+	sessionID := uuid.New()
 	duration := -time.Minute
 
-	tokenString, payload, err := pasetoMaker.CreateToken(username, duration)
+	tokenString, payload, err := pasetoMaker.CreateToken(username, role, sessionID, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, tokenString)
 	require.NotEmpty(t, payload)
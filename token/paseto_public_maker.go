@@ -0,0 +1,67 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/o1egl/paseto"
+)
+
+// PasetoPublicMaker is a PASETO v2.public token maker
+// Tokens are signed with an Ed25519 private key and can be verified with the public key only
+type PasetoPublicMaker struct {
+	paseto     *paseto.V2
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+// NewPasetoPublicMaker returns new instance of PasetoPublicMaker
+// The private key may be nil, in which case the maker can only verify tokens
+func NewPasetoPublicMaker(privateKey ed25519.PrivateKey, publicKey ed25519.PublicKey) (Maker, error) {
+	if err := validateEd25519Keys(privateKey, publicKey); err != nil {
+		return nil, err
+	}
+
+	maker := &PasetoPublicMaker{
+		paseto:     paseto.NewV2(),
+		privateKey: privateKey,
+		publicKey:  publicKey,
+	}
+
+	return maker, nil
+}
+
+// CreateToken creates new signed token for a given username and role
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, sessionID uuid.UUID, duration time.Duration) (string, *Payload, error) {
+	if maker.privateKey == nil {
+		return "", nil, ErrMissingPrivateKey
+	}
+
+	payload, err := NewPayload(username, role, sessionID, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := maker.paseto.Sign(maker.privateKey, payload, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, payload, nil
+}
+
+// VerifyToken verifies the token signature and returns the token payload
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	payload := &Payload{}
+
+	if err := maker.paseto.Verify(token, maker.publicKey, payload, nil); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if err := payload.Valid(); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
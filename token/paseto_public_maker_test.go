@@ -0,0 +1,93 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func newTestEd25519Keys(t *testing.T) (ed25519.PrivateKey, ed25519.PublicKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return privateKey, publicKey
+}
+
+func TestPasetoPublicToken(t *testing.T) {
+	privateKey, publicKey := newTestEd25519Keys(t)
+
+	pasetoMaker, err := NewPasetoPublicMaker(privateKey, publicKey)
+	require.NoError(t, err)
+	require.NotEmpty(t, pasetoMaker)
+
+	username := util.RandomOwner()
+	role := util.DepositorRole
+	sessionID := uuid.New()
+	duration := time.Minute
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	tokenString, payload, err := pasetoMaker.CreateToken(username, role, sessionID, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, tokenString)
+	require.NotEmpty(t, payload)
+
+	verifier, err := NewPasetoPublicMaker(nil, publicKey)
+	require.NoError(t, err)
+
+	payload, err = verifier.VerifyToken(tokenString)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, payload.Username, username)
+	require.Equal(t, payload.Role, role)
+	require.Equal(t, payload.SessionID, sessionID)
+	require.WithinDuration(t, payload.ExpireAt, expiredAt, time.Second)
+	require.WithinDuration(t, payload.IssuedAt, issuedAt, time.Second)
+
+	_, _, err = verifier.CreateToken(username, role, sessionID, duration)
+	require.EqualError(t, err, ErrMissingPrivateKey.Error())
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	privateKey, publicKey := newTestEd25519Keys(t)
+
+	pasetoMaker, err := NewPasetoPublicMaker(privateKey, publicKey)
+	require.NoError(t, err)
+
+	tokenString, payload, err := pasetoMaker.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), -time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, tokenString)
+	require.NotEmpty(t, payload)
+
+	payload, err = pasetoMaker.VerifyToken(tokenString)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestPasetoPublicTokenWrongKey(t *testing.T) {
+	privateKey, publicKey := newTestEd25519Keys(t)
+	_, otherPublicKey := newTestEd25519Keys(t)
+
+	_, err := NewPasetoPublicMaker(privateKey, otherPublicKey)
+	require.Error(t, err)
+
+	pasetoMaker, err := NewPasetoPublicMaker(privateKey, publicKey)
+	require.NoError(t, err)
+
+	tokenString, _, err := pasetoMaker.CreateToken(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	verifier, err := NewPasetoPublicMaker(nil, otherPublicKey)
+	require.NoError(t, err)
+
+	payload, err := verifier.VerifyToken(tokenString)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
@@ -11,28 +11,43 @@ import (
 var (
 	ErrExpiredToken = errors.New("token has expired")
 	ErrInvalidToken = errors.New("token is invalid")
+	// ErrIssuedBeforePasswordChange is returned for tokens issued before the user last changed the password
+	ErrIssuedBeforePasswordChange = errors.New("token was issued before the last password change")
+	// ErrMissingPrivateKey is returned when a verification-only maker is asked to create a token
+	ErrMissingPrivateKey = errors.New("private key is required to create token")
+	// ErrChallengeToken is returned when a two-factor challenge token is used in place of an access token
+	ErrChallengeToken = errors.New("challenge token cannot be used as access token")
 )
 
+// ChallengeRole is the role of the short-lived tokens returned by the first step of a two-factor login
+// The challenge tokens are not tied to any session and only prove that the password was correct
+const ChallengeRole = "totp_challenge"
+
 // Payload contains the payload data for the token
 type Payload struct {
-	ID       uuid.UUID `json: "id"`
-	Username string    `json: "username"`
-	IssuedAt time.Time `json: "issued_at"`
-	ExpireAt time.Time `json: "expire_at"`
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	// SessionID ties the token to the login session, so it can be revoked with the session
+	SessionID uuid.UUID `json:"session_id"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpireAt  time.Time `json:"expire_at"`
 }
 
 // NewPayload returns new payload object
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+func NewPayload(username string, role string, sessionID uuid.UUID, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
 	}
 
 	payload := &Payload{
-		ID:       tokenID,
-		Username: username,
-		IssuedAt: time.Now(),
-		ExpireAt: time.Now().Add(duration),
+		ID:        tokenID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID,
+		IssuedAt:  time.Now(),
+		ExpireAt:  time.Now().Add(duration),
 	}
 
 	return payload, nil
@@ -46,3 +61,18 @@ func (p *Payload) Valid() error {
 
 	return nil
 }
+
+// CheckPasswordChange returns an error if the token was issued before the password was last changed
+// Both times are compared in UTC, whatever location the token or the database driver decoded them in
+func (p *Payload) CheckPasswordChange(passwordChangeAt time.Time) error {
+	if p.IssuedAt.UTC().Before(passwordChangeAt.UTC()) {
+		return ErrIssuedBeforePasswordChange
+	}
+
+	return nil
+}
+
+// IsChallenge returns true if the token is a two-factor challenge token
+func (p *Payload) IsChallenge() bool {
+	return p.Role == ChallengeRole
+}
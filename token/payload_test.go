@@ -0,0 +1,39 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+func TestCheckPasswordChange(t *testing.T) {
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+
+	// the password was never changed
+	require.NoError(t, payload.CheckPasswordChange(time.Time{}))
+	require.NoError(t, payload.CheckPasswordChange(payload.IssuedAt.Add(-time.Second)))
+
+	err = payload.CheckPasswordChange(payload.IssuedAt.Add(time.Second))
+	require.EqualError(t, err, ErrIssuedBeforePasswordChange.Error())
+
+	// the location of the password change time does not matter
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	require.NoError(t, payload.CheckPasswordChange(payload.IssuedAt.Add(-time.Second).In(zone)))
+
+	err = payload.CheckPasswordChange(payload.IssuedAt.Add(time.Second).In(zone))
+	require.EqualError(t, err, ErrIssuedBeforePasswordChange.Error())
+}
+
+func TestIsChallenge(t *testing.T) {
+	payload, err := NewPayload(util.RandomOwner(), ChallengeRole, uuid.Nil, time.Minute)
+	require.NoError(t, err)
+	require.True(t, payload.IsChallenge())
+
+	payload, err = NewPayload(util.RandomOwner(), util.DepositorRole, uuid.New(), time.Minute)
+	require.NoError(t, err)
+	require.False(t, payload.IsChallenge())
+}
@@ -9,13 +9,42 @@ import (
 // Config store application configuration
 // Viper reads configuration from file or env variables
 type Config struct {
-	DBDriver             string        `mapstructure:"DB_DRIVER"`
-	DBSource             string        `mapstructure:"DB_SOURCE"`
-	MigrationURL         string        `mapstructure:"MIGRATION_URL"`
-	ServerAddress        string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
-	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	DBDriver                string        `mapstructure:"DB_DRIVER"`
+	DBSource                string        `mapstructure:"DB_SOURCE"`
+	MigrationURL            string        `mapstructure:"MIGRATION_URL"`
+	ServerMode              string        `mapstructure:"SERVER_MODE"`
+	HTTPServerAddress       string        `mapstructure:"HTTP_SERVER_ADDRESS"`
+	GRPCServerAddress       string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	TrustedProxies          []string      `mapstructure:"TRUSTED_PROXIES"`
+	TokenType               string        `mapstructure:"TOKEN_TYPE"`
+	TokenSymmetricKey       string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenSymmetricKeys      string        `mapstructure:"TOKEN_SYMMETRIC_KEYS"`
+	TokenActiveKeyID        string        `mapstructure:"TOKEN_ACTIVE_KEY_ID"`
+	TokenPrivateKeyFile     string        `mapstructure:"TOKEN_PRIVATE_KEY_FILE"`
+	TokenPublicKeyFile      string        `mapstructure:"TOKEN_PUBLIC_KEY_FILE"`
+	TokenDenylist           string        `mapstructure:"TOKEN_DENYLIST"`
+	LoginLimiter            string        `mapstructure:"LOGIN_LIMITER"`
+	LoginMaxAttempts        int32         `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginMaxAttemptsPerIP   int32         `mapstructure:"LOGIN_MAX_ATTEMPTS_PER_IP"`
+	LoginLockoutDuration    time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginMaxLockoutDuration time.Duration `mapstructure:"LOGIN_MAX_LOCKOUT_DURATION"`
+	TOTPIssuer              string        `mapstructure:"TOTP_ISSUER"`
+	TOTPChallengeDuration   time.Duration `mapstructure:"TOTP_CHALLENGE_DURATION"`
+	AccessTokenDuration     time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration    time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	ExchangeRatesFile       string        `mapstructure:"EXCHANGE_RATES_FILE"`
+	ShutdownTimeout         time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	LogLevel                string        `mapstructure:"LOG_LEVEL"`
+	EmailSenderType         string        `mapstructure:"EMAIL_SENDER_TYPE"`
+	EmailFromAddress        string        `mapstructure:"EMAIL_FROM_ADDRESS"`
+	SMTPAddress             string        `mapstructure:"SMTP_ADDRESS"`
+	SMTPUsername            string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword            string        `mapstructure:"SMTP_PASSWORD"`
+	EmailOutboxDir          string        `mapstructure:"EMAIL_OUTBOX_DIR"`
+	VerifyEmailURL          string        `mapstructure:"VERIFY_EMAIL_URL"`
+	PasswordResetURL        string        `mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetDuration   time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+	TaskPollInterval        time.Duration `mapstructure:"TASK_POLL_INTERVAL"`
 }
 
 // LoadConfig reads a configuration from file or enviroment variables
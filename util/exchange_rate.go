@@ -0,0 +1,79 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+var ErrUnsupportedExchange = errors.New("unsupported currency exchange")
+
+// ExchangeRateProvider returns the rate to convert an amount from one currency to another
+type ExchangeRateProvider interface {
+	GetExchangeRate(from string, to string) (float64, error)
+}
+
+// ExchangeRates maps a source currency to the rates of the destination currencies
+type ExchangeRates map[string]map[string]float64
+
+// defaultExchangeRates is a static table of rates for local use
+var defaultExchangeRates = ExchangeRates{
+	USD: {EUR: 0.92, CAN: 1.36},
+	EUR: {USD: 1.09, CAN: 1.48},
+	CAN: {USD: 0.74, EUR: 0.68},
+}
+
+// StaticExchangeRateProvider is an ExchangeRateProvider backed by a fixed table of rates
+type StaticExchangeRateProvider struct {
+	rates ExchangeRates
+}
+
+// NewStaticExchangeRateProvider returns new provider for the given table of rates
+func NewStaticExchangeRateProvider(rates ExchangeRates) ExchangeRateProvider {
+	return &StaticExchangeRateProvider{rates: rates}
+}
+
+// NewExchangeRateProvider returns a provider with the rates from the JSON file at the given path
+// or with the default static rates if the path is empty
+func NewExchangeRateProvider(path string) (ExchangeRateProvider, error) {
+	if len(path) == 0 {
+		return NewStaticExchangeRateProvider(defaultExchangeRates), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates file: %w", err)
+	}
+
+	var rates ExchangeRates
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates file: %w", err)
+	}
+
+	return NewStaticExchangeRateProvider(rates), nil
+}
+
+// GetExchangeRate returns the rate from the table, the inverse of the opposite rate
+// or 1 for the same currency
+func (provider *StaticExchangeRateProvider) GetExchangeRate(from string, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	if rate, ok := provider.rates[from][to]; ok && rate > 0 {
+		return rate, nil
+	}
+
+	if rate, ok := provider.rates[to][from]; ok && rate > 0 {
+		return 1 / rate, nil
+	}
+
+	return 0, fmt.Errorf("%w: %s to %s", ErrUnsupportedExchange, from, to)
+}
+
+// ConvertAmount converts the amount with the given rate rounding to the nearest unit
+func ConvertAmount(amount int64, rate float64) int64 {
+	return int64(math.Round(float64(amount) * rate))
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticExchangeRateProvider(t *testing.T) {
+	provider := NewStaticExchangeRateProvider(ExchangeRates{
+		USD: {EUR: 0.5},
+	})
+
+	rate, err := provider.GetExchangeRate(USD, USD)
+	require.NoError(t, err)
+	require.Equal(t, float64(1), rate)
+
+	rate, err = provider.GetExchangeRate(USD, EUR)
+	require.NoError(t, err)
+	require.Equal(t, 0.5, rate)
+
+	// inverse of the opposite rate
+	rate, err = provider.GetExchangeRate(EUR, USD)
+	require.NoError(t, err)
+	require.Equal(t, float64(2), rate)
+
+	_, err = provider.GetExchangeRate(USD, CAN)
+	require.ErrorIs(t, err, ErrUnsupportedExchange)
+
+	require.Equal(t, int64(50), ConvertAmount(100, 0.5))
+	require.Equal(t, int64(1), ConvertAmount(1, 0.5))
+}
+
+func TestFileExchangeRateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(path, []byte(`{"EUR": {"CAN": 1.5}}`), 0600)
+	require.NoError(t, err)
+
+	provider, err := NewExchangeRateProvider(path)
+	require.NoError(t, err)
+
+	rate, err := provider.GetExchangeRate(EUR, CAN)
+	require.NoError(t, err)
+	require.Equal(t, 1.5, rate)
+
+	_, err = NewExchangeRateProvider(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+)
+
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// EncodePageToken returns an opaque token pointing after the row with the given id
+func EncodePageToken(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// DecodePageToken returns the row id the token points after
+// An empty token points at the beginning of the list
+func DecodePageToken(token string) (int64, error) {
+	if len(token) == 0 {
+		return 0, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidPageToken
+	}
+
+	id, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || id < 0 {
+		return 0, ErrInvalidPageToken
+	}
+
+	return id, nil
+}
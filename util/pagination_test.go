@@ -0,0 +1,30 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageToken(t *testing.T) {
+	id := RandomInt(1, 1000)
+
+	token := EncodePageToken(id)
+	require.NotEmpty(t, token)
+
+	decodedID, err := DecodePageToken(token)
+	require.NoError(t, err)
+	require.Equal(t, id, decodedID)
+
+	// empty token points at the beginning of the list
+	decodedID, err = DecodePageToken("")
+	require.NoError(t, err)
+	require.Zero(t, decodedID)
+
+	// malformed tokens are rejected
+	_, err = DecodePageToken(RandomString(6) + "!")
+	require.EqualError(t, err, ErrInvalidPageToken.Error())
+
+	_, err = DecodePageToken(EncodePageToken(-id))
+	require.EqualError(t, err, ErrInvalidPageToken.Error())
+}
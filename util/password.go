@@ -1,7 +1,10 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"sync"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -15,6 +18,28 @@ func HashPassword(password string) (string, error) {
 	return string(bcrypt), nil
 }
 
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// SimulatePasswordCheck runs the same bcrypt comparison as CheckPassword against a dummy hash
+// so a login with an unknown username takes as long to reject as a wrong password
+func SimulatePasswordCheck(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	})
+
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
 func CheckPassword(password string, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+// HashToken returns the sha256 hash of a random single-use token
+// Unlike passwords the tokens have enough entropy, so a fast hash is enough to store them
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
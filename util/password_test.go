@@ -30,3 +30,12 @@ func TestPasswod(t *testing.T) {
 	require.NotEmpty(t, hashedPassword2)
 	require.NotEqual(t, hashedPassword1, hashedPassword2)
 }
+
+func TestHashToken(t *testing.T) {
+	token := RandomString(32)
+
+	hashedToken := HashToken(token)
+	require.Len(t, hashedToken, 64)
+	require.Equal(t, hashedToken, HashToken(token))
+	require.NotEqual(t, hashedToken, HashToken(RandomString(32)))
+}
@@ -0,0 +1,18 @@
+package util
+
+// Constants for the user roles
+const (
+	DepositorRole = "depositor"
+	BankerRole    = "banker"
+	AdminRole     = "admin"
+)
+
+// CanViewAnyAccount returns true if the role can view the accounts of other users
+func CanViewAnyAccount(role string) bool {
+	switch role {
+	case BankerRole, AdminRole:
+		return true
+	}
+
+	return false
+}
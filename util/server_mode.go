@@ -0,0 +1,19 @@
+package util
+
+// Constants for the servers the application can run
+const (
+	ServerModeGin     = "gin"
+	ServerModeGRPC    = "grpc"
+	ServerModeGateway = "gateway"
+	ServerModeAll     = "all"
+)
+
+// IsSupportedServerMode returns true if the server mode is supported
+func IsSupportedServerMode(mode string) bool {
+	switch mode {
+	case ServerModeGin, ServerModeGRPC, ServerModeGateway, ServerModeAll:
+		return true
+	}
+
+	return false
+}
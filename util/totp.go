@@ -0,0 +1,148 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Parameters of the generated TOTP codes, authenticator apps assume these when the URI omits them
+const (
+	TOTPDigits = 6
+	TOTPPeriod = 30 * time.Second
+	// totpSkew is the number of periods accepted before and after the current one to allow for clock drift
+	totpSkew = 1
+	// totpSecretSize is the size of the secret in bytes, as recommended for HMAC-SHA1 by RFC 4226
+	totpSecretSize = 20
+	// recoveryCodeSize is the number of random bytes in a recovery code
+	recoveryCodeSize = 5
+)
+
+var (
+	totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+	// validRecoveryCode matches the codes of GenerateRecoveryCodes
+	validRecoveryCode = regexp.MustCompile(`^[0-9a-f]{5}-[0-9a-f]{5}$`).MatchString
+)
+
+// GenerateTOTPSecret returns new random base32 encoded TOTP secret
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPURI returns the otpauth URI of the secret, which authenticator apps read from a QR code
+func TOTPURI(issuer string, accountName string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTPDigits))
+	query.Set("period", fmt.Sprint(int(TOTPPeriod.Seconds())))
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}
+
+	return uri.String()
+}
+
+// GenerateTOTPCode returns the RFC 6238 code of the secret for the period containing t
+func GenerateTOTPCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	return hotp(key, uint64(TOTPStep(t))), nil
+}
+
+// TOTPStep returns the number of the period containing t, which the TOTP code is generated for
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod.Seconds())
+}
+
+// CheckTOTPCode checks the code against the secret within the allowed clock skew
+// Returns the step of the matching code, so the callers can reject codes that were already used
+func CheckTOTPCode(secret string, code string, t time.Time) (int64, bool) {
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	for skew := -totpSkew; skew <= totpSkew; skew++ {
+		at := t.Add(time.Duration(skew) * TOTPPeriod)
+
+		expected, err := GenerateTOTPCode(secret, at)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return TOTPStep(at), true
+		}
+	}
+
+	return 0, false
+}
+
+// hotp returns the RFC 4226 code of the key for the counter
+func hotp(key []byte, counter uint64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", TOTPDigits, value%modulo)
+}
+
+// GenerateRecoveryCodes returns n random single-use recovery codes in the form xxxxx-xxxxx
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+
+	for i := range codes {
+		code := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(code); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		encoded := hex.EncodeToString(code)
+		codes[i] = encoded[:recoveryCodeSize] + "-" + encoded[recoveryCodeSize:]
+	}
+
+	return codes, nil
+}
+
+// NormalizeRecoveryCode trims and lowercases the recovery code entered by the user
+func NormalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
+
+// IsRecoveryCode returns true if the normalized code has the form of the generated recovery codes
+func IsRecoveryCode(code string) bool {
+	return validRecoveryCode(NormalizeRecoveryCode(code))
+}
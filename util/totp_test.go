@@ -0,0 +1,98 @@
+package util
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateTOTPCode(t *testing.T) {
+	// test vectors of RFC 6238 for HMAC-SHA1, truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	testCases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, expected := range testCases {
+		code, err := GenerateTOTPCode(secret, time.Unix(unix, 0))
+		require.NoError(t, err)
+		require.Equal(t, expected, code)
+	}
+
+	_, err := GenerateTOTPCode("not base32!", time.Now())
+	require.Error(t, err)
+}
+
+func TestCheckTOTPCode(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	code, err := GenerateTOTPCode(secret, now)
+	require.NoError(t, err)
+	require.Len(t, code, TOTPDigits)
+
+	step, ok := CheckTOTPCode(secret, code, now)
+	require.True(t, ok)
+	require.Equal(t, TOTPStep(now), step)
+
+	// the code of the previous period is still accepted to allow for clock drift
+	step, ok = CheckTOTPCode(secret, code, now.Add(TOTPPeriod))
+	require.True(t, ok)
+	require.Equal(t, TOTPStep(now), step)
+
+	_, ok = CheckTOTPCode(secret, code, now.Add(3*TOTPPeriod))
+	require.False(t, ok)
+
+	_, ok = CheckTOTPCode(secret, "", now)
+	require.False(t, ok)
+
+	_, ok = CheckTOTPCode(secret, code+"0", now)
+	require.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	uri, err := url.Parse(TOTPURI("Simple Bank", "alice", secret))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/Simple Bank:alice", uri.Path)
+	require.Equal(t, secret, uri.Query().Get("secret"))
+	require.Equal(t, "Simple Bank", uri.Query().Get("issuer"))
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		require.Regexp(t, `^[0-9a-f]{5}-[0-9a-f]{5}$`, code)
+		require.Equal(t, code, NormalizeRecoveryCode(" "+code+" "))
+		require.True(t, IsRecoveryCode(strings.ToUpper(code)))
+		require.False(t, seen[code])
+		seen[code] = true
+	}
+}
+
+func TestIsRecoveryCode(t *testing.T) {
+	require.True(t, IsRecoveryCode("0a1b2-c3d4e"))
+	require.True(t, IsRecoveryCode(" 0A1B2-C3D4E "))
+
+	require.False(t, IsRecoveryCode("123456"))
+	require.False(t, IsRecoveryCode("0a1b2c3d4e"))
+	require.False(t, IsRecoveryCode("0a1b2-c3d4g"))
+}
@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"net/mail"
 	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/vladoohr/simple_bank/util"
 )
 
 var (
 	validUsername = regexp.MustCompile(`^[a-z0-9_]+$`).MatchString
 	validFullname = regexp.MustCompile(`^[A-Za-z\s]+$`).MatchString
+	validTOTPCode = regexp.MustCompile(`^[0-9]+$`).MatchString
 )
 
 func validateStringLen(value string, minLength int, maxLength int) error {
@@ -63,3 +67,91 @@ func ValidateFullName(value string) error {
 
 	return nil
 }
+
+func ValidateCurrency(value string) error {
+	if !util.IsSupportedCurrency(value) {
+		return fmt.Errorf("unsupported currency")
+	}
+
+	return nil
+}
+
+func ValidateAccountID(value int64) error {
+	if value < 1 {
+		return fmt.Errorf("must be a positive integer")
+	}
+
+	return nil
+}
+
+func ValidatePageID(value int32) error {
+	if value < 1 {
+		return fmt.Errorf("must be a positive integer")
+	}
+
+	return nil
+}
+
+func ValidatePageSize(value int32) error {
+	if value < 5 || value > 20 {
+		return fmt.Errorf("must be from 5-20")
+	}
+
+	return nil
+}
+
+func ValidateAmount(value int64) error {
+	if value <= 0 {
+		return fmt.Errorf("must be greater than 0")
+	}
+
+	return nil
+}
+
+func ValidateIdempotencyKey(value string) error {
+	if len(value) > 255 {
+		return fmt.Errorf("must contain at most 255 characters")
+	}
+
+	return nil
+}
+
+func ValidateSessionID(value string) error {
+	if _, err := uuid.Parse(value); err != nil {
+		return fmt.Errorf("must be a valid UUID")
+	}
+
+	return nil
+}
+
+func ValidateEmailID(value int64) error {
+	if value < 1 {
+		return fmt.Errorf("must be a positive integer")
+	}
+
+	return nil
+}
+
+func ValidateSecretCode(value string) error {
+	return validateStringLen(value, 32, 128)
+}
+
+func ValidateResetToken(value string) error {
+	return validateStringLen(value, 32, 128)
+}
+
+func ValidateTOTPCode(value string) error {
+	if len(value) != util.TOTPDigits {
+		return fmt.Errorf("must contain exactly %d digits", util.TOTPDigits)
+	}
+
+	if valid := validTOTPCode(value); !valid {
+		return fmt.Errorf("must contain only digits")
+	}
+
+	return nil
+}
+
+func ValidateLoginCode(value string) error {
+	return validateStringLen(value, util.TOTPDigits, 32)
+}
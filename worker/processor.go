@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/mail"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+const (
+	// taskBatchSize is the number of tasks claimed by one poll
+	taskBatchSize = 10
+	// taskLease is how long a running task may go without an update before another poll claims it again
+	taskLease = 5 * time.Minute
+	// retryDelay is multiplied by the square of the attempts to back off the failed tasks
+	retryDelay = 10 * time.Second
+)
+
+// TaskHandler runs a single task, the returned error schedules a retry
+type TaskHandler func(ctx context.Context, task db.Task) error
+
+// TaskProcessor polls the tasks table and runs the claimed tasks
+type TaskProcessor struct {
+	config   util.Config
+	store    db.Store
+	sender   mail.EmailSender
+	handlers map[string]TaskHandler
+}
+
+// NewTaskProcessor creates new task processor and registers the task handlers
+func NewTaskProcessor(config util.Config, store db.Store, sender mail.EmailSender) *TaskProcessor {
+	processor := &TaskProcessor{
+		config: config,
+		store:  store,
+		sender: sender,
+	}
+
+	processor.handlers = map[string]TaskHandler{
+		TaskSendVerifyEmail:   processor.handleSendVerifyEmail,
+		TaskSendPasswordReset: processor.handleSendPasswordReset,
+	}
+
+	return processor
+}
+
+// Start polls the tasks until the context is cancelled
+func (processor *TaskProcessor) Start(ctx context.Context) {
+	ticker := time.NewTicker(processor.config.TaskPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := processor.ProcessTasks(ctx); err != nil {
+			log.Error().Err(err).Msg("failed to process tasks")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessTasks claims one batch of due tasks, runs them and returns the number of claimed tasks
+func (processor *TaskProcessor) ProcessTasks(ctx context.Context) (int, error) {
+	tasks, err := processor.store.ClaimTasks(ctx, db.ClaimTasksParams{
+		StaleBefore: time.Now().Add(-taskLease),
+		BatchSize:   taskBatchSize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim tasks: %w", err)
+	}
+
+	for _, task := range tasks {
+		if err := processor.runTask(ctx, task); err != nil {
+			return len(tasks), err
+		}
+	}
+
+	return len(tasks), nil
+}
+
+// runTask runs the handler of the task and records the outcome
+// The returned error is only about recording the outcome, the handler errors are stored on the task
+func (processor *TaskProcessor) runTask(ctx context.Context, task db.Task) error {
+	handler, ok := processor.handlers[task.Type]
+	if !ok {
+		log.Error().Int64("task_id", task.ID).Str("type", task.Type).Msg("unknown task type")
+
+		return processor.store.FailTask(ctx, db.FailTaskParams{
+			ID:        task.ID,
+			LastError: fmt.Sprintf("unknown task type: %s", task.Type),
+		})
+	}
+
+	err := handler(ctx, task)
+	if err == nil {
+		log.Info().Int64("task_id", task.ID).Str("type", task.Type).Msg("processed task")
+		return processor.store.CompleteTask(ctx, task.ID)
+	}
+
+	if task.Attempts >= task.MaxAttempts {
+		log.Error().Err(err).Int64("task_id", task.ID).Str("type", task.Type).Msg("task failed")
+
+		return processor.store.FailTask(ctx, db.FailTaskParams{
+			ID:        task.ID,
+			LastError: err.Error(),
+		})
+	}
+
+	log.Warn().Err(err).Int64("task_id", task.ID).Str("type", task.Type).Int32("attempts", task.Attempts).Msg("task will be retried")
+
+	return processor.store.RetryTask(ctx, db.RetryTaskParams{
+		ID:        task.ID,
+		LastError: err.Error(),
+		RunAt:     time.Now().Add(retryDelay * time.Duration(task.Attempts*task.Attempts)),
+	})
+}
@@ -0,0 +1,224 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	mockdb "github.com/vladoohr/simple_bank/db/mock"
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+// fakeEmailSender records the sent emails and fails when err is set
+type fakeEmailSender struct {
+	err      error
+	contents []string
+	to       [][]string
+}
+
+func (sender *fakeEmailSender) SendEmail(subject string, content string, to []string) error {
+	if sender.err != nil {
+		return sender.err
+	}
+
+	sender.contents = append(sender.contents, content)
+	sender.to = append(sender.to, to)
+
+	return nil
+}
+
+func randomUser() db.User {
+	return db.User{
+		Username: util.RandomOwner(),
+		FullName: util.RandomOwner(),
+		Email:    util.RandomEmail(),
+	}
+}
+
+func verifyEmailTask(t *testing.T, username string, attempts int32) db.Task {
+	params, err := NewSendVerifyEmailTask(PayloadSendVerifyEmail{Username: username})
+	require.NoError(t, err)
+
+	return db.Task{
+		ID:          util.RandomInt(1, 1000),
+		Type:        params.Type,
+		Payload:     params.Payload,
+		Status:      "running",
+		Attempts:    attempts,
+		MaxAttempts: params.MaxAttempts,
+	}
+}
+
+func TestProcessTasks(t *testing.T) {
+	user := randomUser()
+
+	testCases := []struct {
+		name      string
+		task      db.Task
+		senderErr error
+		buildStub func(store *mockdb.MockStore, task db.Task)
+		check     func(t *testing.T, sender *fakeEmailSender)
+	}{
+		{
+			name: "SendVerifyEmail",
+			task: verifyEmailTask(t, user.Username, 1),
+			buildStub: func(store *mockdb.MockStore, task db.Task) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateVerifyEmail(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateVerifyEmailParams) (db.VerifyEmail, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, user.Email, arg.Email)
+						require.Len(t, arg.SecretCode, 2*secretCodeBytes)
+
+						return db.VerifyEmail{ID: 7, Username: arg.Username, Email: arg.Email, SecretCode: arg.SecretCode}, nil
+					})
+				store.EXPECT().CompleteTask(gomock.Any(), gomock.Eq(task.ID)).Times(1).Return(nil)
+			},
+			check: func(t *testing.T, sender *fakeEmailSender) {
+				require.Len(t, sender.contents, 1)
+				require.Equal(t, []string{user.Email}, sender.to[0])
+				require.Contains(t, sender.contents[0], "http://localhost:8080/api/v1/verify_email?email_id=7&amp;secret_code=")
+			},
+		},
+		{
+			name: "AlreadyVerified",
+			task: verifyEmailTask(t, user.Username, 1),
+			buildStub: func(store *mockdb.MockStore, task db.Task) {
+				verifiedUser := user
+				verifiedUser.IsEmailVerified = true
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(verifiedUser, nil)
+				store.EXPECT().CreateVerifyEmail(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CompleteTask(gomock.Any(), gomock.Eq(task.ID)).Times(1).Return(nil)
+			},
+			check: func(t *testing.T, sender *fakeEmailSender) {
+				require.Empty(t, sender.contents)
+			},
+		},
+		{
+			name:      "Retry",
+			task:      verifyEmailTask(t, user.Username, 2),
+			senderErr: errors.New("smtp unavailable"),
+			buildStub: func(store *mockdb.MockStore, task db.Task) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateVerifyEmail(gomock.Any(), gomock.Any()).Times(1).Return(db.VerifyEmail{ID: 1}, nil)
+				store.EXPECT().RetryTask(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RetryTaskParams) error {
+						require.Equal(t, task.ID, arg.ID)
+						require.Contains(t, arg.LastError, "smtp unavailable")
+						require.WithinDuration(t, time.Now().Add(4*retryDelay), arg.RunAt, time.Second)
+
+						return nil
+					})
+			},
+			check: func(t *testing.T, sender *fakeEmailSender) {},
+		},
+		{
+			name: "MaxAttempts",
+			task: verifyEmailTask(t, user.Username, defaultMaxAttempts),
+			buildStub: func(store *mockdb.MockStore, task db.Task) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, errors.New("connection lost"))
+				store.EXPECT().RetryTask(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().FailTask(gomock.Any(), gomock.Eq(db.FailTaskParams{
+					ID:        task.ID,
+					LastError: "failed to get user: connection lost",
+				})).Times(1).Return(nil)
+			},
+			check: func(t *testing.T, sender *fakeEmailSender) {},
+		},
+		{
+			name: "UnknownType",
+			task: db.Task{ID: 1, Type: "task:unknown", Attempts: 1, MaxAttempts: defaultMaxAttempts},
+			buildStub: func(store *mockdb.MockStore, task db.Task) {
+				store.EXPECT().FailTask(gomock.Any(), gomock.Eq(db.FailTaskParams{
+					ID:        task.ID,
+					LastError: "unknown task type: task:unknown",
+				})).Times(1).Return(nil)
+			},
+			check: func(t *testing.T, sender *fakeEmailSender) {},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().ClaimTasks(gomock.Any(), gomock.Any()).Times(1).Return([]db.Task{tc.task}, nil)
+			tc.buildStub(store, tc.task)
+
+			sender := &fakeEmailSender{err: tc.senderErr}
+			config := util.Config{VerifyEmailURL: "http://localhost:8080/api/v1/verify_email"}
+
+			processor := NewTaskProcessor(config, store, sender)
+
+			claimed, err := processor.ProcessTasks(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, claimed)
+
+			tc.check(t, sender)
+		})
+	}
+}
+
+func TestSendPasswordReset(t *testing.T) {
+	user := randomUser()
+
+	params, err := NewSendPasswordResetTask(PayloadSendPasswordReset{Username: user.Username})
+	require.NoError(t, err)
+
+	task := db.Task{
+		ID:          util.RandomInt(1, 1000),
+		Type:        params.Type,
+		Payload:     params.Payload,
+		Attempts:    1,
+		MaxAttempts: params.MaxAttempts,
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var tokenHash string
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ClaimTasks(gomock.Any(), gomock.Any()).Times(1).Return([]db.Task{task}, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().CreatePasswordReset(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreatePasswordResetParams) (db.PasswordReset, error) {
+			require.Equal(t, user.Username, arg.Username)
+			require.WithinDuration(t, time.Now().Add(15*time.Minute), arg.ExpiredAt, time.Second)
+			tokenHash = arg.TokenHash
+
+			return db.PasswordReset{ID: 1, Username: arg.Username, TokenHash: arg.TokenHash, ExpiredAt: arg.ExpiredAt}, nil
+		})
+	store.EXPECT().CompleteTask(gomock.Any(), gomock.Eq(task.ID)).Times(1).Return(nil)
+
+	sender := &fakeEmailSender{}
+	config := util.Config{
+		PasswordResetURL:      "http://localhost:8080/reset_password",
+		PasswordResetDuration: 15 * time.Minute,
+	}
+
+	processor := NewTaskProcessor(config, store, sender)
+
+	_, err = processor.ProcessTasks(context.Background())
+	require.NoError(t, err)
+
+	// only the hash is stored, the token itself is sent to the user
+	require.Len(t, sender.contents, 1)
+	prefix := "http://localhost:8080/reset_password?token="
+	start := strings.Index(sender.contents[0], prefix)
+	require.NotEqual(t, -1, start)
+
+	resetToken := sender.contents[0][start+len(prefix) : start+len(prefix)+2*resetTokenBytes]
+	require.NotEqual(t, resetToken, tokenHash)
+	require.Equal(t, util.HashToken(resetToken), tokenHash)
+}
@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+)
+
+// Constants for the task types handled by the processor
+const (
+	TaskSendVerifyEmail   = "task:send_verify_email"
+	TaskSendPasswordReset = "task:send_password_reset"
+)
+
+// defaultMaxAttempts is the number of times a task is run before it is marked as failed
+const defaultMaxAttempts = 5
+
+// PayloadSendVerifyEmail contains the user the verification email is sent to
+type PayloadSendVerifyEmail struct {
+	Username string `json:"username"`
+}
+
+// NewSendVerifyEmailTask returns the parameters to enqueue a verification email for the user
+func NewSendVerifyEmailTask(payload PayloadSendVerifyEmail) (db.CreateTaskParams, error) {
+	return newTask(TaskSendVerifyEmail, payload)
+}
+
+// PayloadSendPasswordReset contains the user the password reset link is sent to
+type PayloadSendPasswordReset struct {
+	Username string `json:"username"`
+}
+
+// NewSendPasswordResetTask returns the parameters to enqueue a password reset email for the user
+func NewSendPasswordResetTask(payload PayloadSendPasswordReset) (db.CreateTaskParams, error) {
+	return newTask(TaskSendPasswordReset, payload)
+}
+
+func newTask(taskType string, payload interface{}) (db.CreateTaskParams, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return db.CreateTaskParams{}, fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	params := db.CreateTaskParams{
+		Type:        taskType,
+		Payload:     data,
+		MaxAttempts: defaultMaxAttempts,
+		RunAt:       time.Now(),
+	}
+
+	return params, nil
+}
@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"time"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+	"github.com/vladoohr/simple_bank/util"
+)
+
+// resetTokenBytes is the number of random bytes in the password reset token
+const resetTokenBytes = 32
+
+// handleSendPasswordReset stores the hash of a new reset token and emails the token to the user
+// Only the user receives the token itself, so a leaked table can not be used to reset passwords
+func (processor *TaskProcessor) handleSendPasswordReset(ctx context.Context, task db.Task) error {
+	var payload PayloadSendPasswordReset
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	user, err := processor.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	resetToken, err := newSecret(resetTokenBytes)
+	if err != nil {
+		return err
+	}
+
+	passwordReset, err := processor.store.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: util.HashToken(resetToken),
+		ExpiredAt: time.Now().Add(processor.config.PasswordResetDuration),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("token", resetToken)
+	resetURL := fmt.Sprintf("%s?%s", processor.config.PasswordResetURL, query.Encode())
+
+	subject := "Reset your Simple Bank password"
+	content := fmt.Sprintf(`Hello %s,<br/>
+	We received a request to reset your password.<br/>
+	Please <a href="%s">click here</a> to choose a new password, the link expires at %s.<br/>
+	If you did not request it, you can ignore this email.<br/>
+	`, html.EscapeString(user.FullName), html.EscapeString(resetURL), passwordReset.ExpiredAt.UTC().Format(time.RFC1123))
+
+	if err := processor.sender.SendEmail(subject, content, []string{user.Email}); err != nil {
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+
+	return nil
+}
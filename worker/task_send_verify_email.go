@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+
+	db "github.com/vladoohr/simple_bank/db/sqlc"
+)
+
+// secretCodeBytes is the number of random bytes in the verification code
+const secretCodeBytes = 16
+
+// handleSendVerifyEmail stores a new verification code for the user and emails the verification link
+func (processor *TaskProcessor) handleSendVerifyEmail(ctx context.Context, task db.Task) error {
+	var payload PayloadSendVerifyEmail
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	user, err := processor.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.IsEmailVerified {
+		return nil
+	}
+
+	secretCode, err := newSecret(secretCodeBytes)
+	if err != nil {
+		return err
+	}
+
+	verifyEmail, err := processor.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: secretCode,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create verify email: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("email_id", strconv.FormatInt(verifyEmail.ID, 10))
+	query.Set("secret_code", verifyEmail.SecretCode)
+	verifyURL := fmt.Sprintf("%s?%s", processor.config.VerifyEmailURL, query.Encode())
+
+	subject := "Welcome to Simple Bank"
+	content := fmt.Sprintf(`Hello %s,<br/>
+	Thank you for registering with us!<br/>
+	Please <a href="%s">click here</a> to verify your email address.<br/>
+	`, html.EscapeString(user.FullName), html.EscapeString(verifyURL))
+
+	if err := processor.sender.SendEmail(subject, content, []string{user.Email}); err != nil {
+		return fmt.Errorf("failed to send verify email: %w", err)
+	}
+
+	return nil
+}
+
+// newSecret returns hex encoded random bytes of the given size
+func newSecret(size int) (string, error) {
+	secret := make([]byte, size)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	return hex.EncodeToString(secret), nil
+}